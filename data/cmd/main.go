@@ -4,9 +4,13 @@ import (
 	"context"
 	"github.com/WLM1ke/poptimizer/data/internal/api"
 	"github.com/WLM1ke/poptimizer/data/internal/bus"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/end"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
 	"github.com/WLM1ke/poptimizer/data/pkg/app"
 	"github.com/WLM1ke/poptimizer/data/pkg/client"
 	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"github.com/WLM1ke/poptimizer/data/pkg/server"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"net/http"
 	"time"
 )
@@ -15,9 +19,64 @@ type data struct {
 	Server struct {
 		Addr    string        `envDefault:"localhost:3000"`
 		Timeout time.Duration `envDefault:"1s"`
+		// Токен для запросов, изменяющих состояние сервиса, - без него такие запросы запрещены
+		Token string `env:"SERVER_TOKEN,unset" envDefault:""`
+	}
+	Metrics struct {
+		// Адрес отдельного сервера с метриками в формате Prometheus
+		Addr string `envDefault:"localhost:3001"`
 	}
 	Events struct {
 		Timeout time.Duration `envDefault:"30s"`
+		// Ограничение времени на завершение выполняющихся обновлений при остановке сервиса
+		DrainTimeout time.Duration `envDefault:"1m"`
+		// Срок хранения обработанных событий в журнале
+		LogTTL time.Duration `envDefault:"2160h"`
+		// Общее количество попыток обновления таблицы при временных ошибках
+		RetryAttempts int `envDefault:"3"`
+		// Задержка перед первой повторной попыткой, которая удваивается для последующих
+		RetryDelay time.Duration `envDefault:"10s"`
+		// Максимальное количество одновременно обновляемых одним правилом таблиц
+		Concurrency int `envDefault:"8"`
+		// Ограничения для отдельных правил в формате Rule:limit
+		RuleConcurrency []string `envDefault:"QuotesRule:4,DividendsRule:4"`
+		// Количество временных ошибок подряд, после которого источник данных признается недоступным
+		BreakerThreshold int `envDefault:"5"`
+		// Пауза перед пробным обращением к недоступному источнику данных
+		BreakerCooldown time.Duration `envDefault:"5m"`
+	}
+	Schedule struct {
+		// Часовой пояс, в котором задано время публикации данных
+		TZ string `envDefault:"Europe/Moscow"`
+		// Время публикации итогов торгов на MOEX ISS на следующий день
+		ISS string `envDefault:"00:45"`
+		// День месяца и время публикации Росстатом индекса потребительских цен за предыдущий месяц
+		CPIDay int    `envDefault:"15"`
+		CPI    string `envDefault:"19:00"`
+		// Праздничные дни и рабочие выходные MOEX в формате 2006-01-02 - нужны только для дат, котировки индекса
+		// МосБиржи за которые еще не загружены, так как торговый календарь строится по ним
+		Holidays []string `envDefault:""`
+		Workdays []string `envDefault:""`
+		// Праздничные дни и рабочие выходные валютного рынка MOEX в формате 2006-01-02 для проверки пропусков в курсе
+		// доллара
+		CurrencyHolidays []string `envDefault:""`
+		CurrencyWorkdays []string `envDefault:""`
+	}
+	CBR struct {
+		// Допустимое относительное расхождение курса ЦБ РФ и биржевого курса доллара
+		Tolerance float64 `envDefault:"0.02"`
+	}
+	Liquidity struct {
+		// Положительные окна в торговых днях для расчета медианного оборота
+		Windows []int `envDefault:"21,63,252"`
+	}
+	Quality struct {
+		// Количество стандартных отклонений дневного изменения, начиная с которого оно считается выбросом
+		Sigma float64 `envDefault:"6"`
+	}
+	Indexes struct {
+		// Загружаемые индексы в формате TICKER или TICKER:market
+		List []string `envDefault:"MCFTRR,MEOGTRR,IMOEX,RVI"`
 	}
 	MongoDB struct {
 		URI string `env:"URI,unset" envDefault:"mongodb://localhost:27017"`
@@ -25,6 +84,8 @@ type data struct {
 	}
 	HTTPClient struct {
 		Connections int `envDefault:"20"`
+		// Ограничения частоты запросов к хостам в формате host:rps:burst
+		RateLimits []string `envDefault:"iss.moex.com:10:10,rosstat.gov.ru:1:2"`
 	}
 	Telegram struct {
 		Token  string `env:"TOKEN,unset"`
@@ -38,7 +99,10 @@ func (d data) Build(logger *lgr.Logger) ([]app.ResourceCloseFunc, []app.Service)
 		logger.Panicf("App: %s", err)
 	}
 
-	httpClient := client.NewHTTPClient(d.HTTPClient.Connections)
+	httpClient, err := client.NewHTTPClient(d.HTTPClient.Connections, d.HTTPClient.RateLimits)
+	if err != nil {
+		logger.Panicf("App: %s", err)
+	}
 
 	resource := []app.ResourceCloseFunc{
 		func(ctx context.Context) error {
@@ -61,19 +125,59 @@ func (d data) Build(logger *lgr.Logger) ([]app.ResourceCloseFunc, []app.Service)
 		logger.Panicf("App: %s", err)
 	}
 
+	eventBus, err := bus.NewEventBus(
+		logger,
+		db,
+		httpClient,
+		telega,
+		bus.Config{
+			Timeout:          d.Events.Timeout,
+			DrainTimeout:     d.Events.DrainTimeout,
+			EventsTTL:        d.Events.LogTTL,
+			USDTolerance:     d.CBR.Tolerance,
+			LiquidityWindows: d.Liquidity.Windows,
+			QualitySigma:     d.Quality.Sigma,
+			IndexList:        d.Indexes.List,
+			Retry:            template.Retry{Attempts: d.Events.RetryAttempts, Delay: d.Events.RetryDelay},
+			Concurrency:      d.Events.Concurrency,
+			RuleConcurrency:  d.Events.RuleConcurrency,
+			Breakers:         template.NewBreakers(d.Events.BreakerThreshold, d.Events.BreakerCooldown),
+			Schedule: end.Settings{
+				TZ:       d.Schedule.TZ,
+				ISS:      d.Schedule.ISS,
+				CPIDay:   d.Schedule.CPIDay,
+				CPI:      d.Schedule.CPI,
+				Holidays: d.Schedule.Holidays,
+				Workdays: d.Schedule.Workdays,
+			},
+			CurrencyHolidays: d.Schedule.CurrencyHolidays,
+			CurrencyWorkdays: d.Schedule.CurrencyWorkdays,
+		},
+	)
+	if err != nil {
+		logger.Panicf("App: %s", err)
+	}
+
+	metricsHandler := http.NewServeMux()
+	metricsHandler.Handle("/metrics", promhttp.Handler())
+
 	services := []app.Service{
 		api.NewHTTPServer(
 			logger,
 			db,
+			eventBus,
+			eventBus.Graph(),
+			eventBus.Schedule(),
+			d.Server.Token,
 			d.Server.Addr,
 			d.Server.Timeout,
 		),
-		bus.NewEventBus(
+		eventBus,
+		server.NewServer(
 			logger,
-			db,
-			httpClient,
-			telega,
-			d.Events.Timeout,
+			d.Metrics.Addr,
+			metricsHandler,
+			d.Server.Timeout,
 		),
 	}
 
@@ -2,8 +2,10 @@ package api
 
 import (
 	"errors"
+	"github.com/WLM1ke/poptimizer/data/internal/bus"
 	"github.com/WLM1ke/poptimizer/data/internal/domain"
 	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/end"
 	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
 	"github.com/WLM1ke/poptimizer/data/pkg/server"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -14,8 +16,31 @@ import (
 )
 
 // jsonHandler основной обработчик отдающий данные в формате BSON для http-сервера.
-func jsonHandler(logger *lgr.Logger, viewer repo.JSONViewer) http.Handler {
+func jsonHandler(
+	logger *lgr.Logger,
+	viewer repo.JSONViewer,
+	metas repo.MetaViewer,
+	letters *repo.DeadLetters,
+	log *repo.EventLog,
+	statuses *repo.TableStatuses,
+	publisher Publisher,
+	graph bus.Graph,
+	schedule end.Schedule,
+	token string,
+) http.Handler {
 	router := chi.NewRouter()
+	router.Get("/graph", graphHandler(logger, graph))
+	router.Get("/tables", tablesHandler(logger, statuses, graph))
+	router.Get("/events/{correlation}", chainHandler(logger, log))
+	router.Get("/dead_letters", deadLettersListHandler(logger, letters))
+	router.Group(func(router chi.Router) {
+		router.Use(authenticated(token))
+		router.Post("/refresh", refreshPipelineHandler(logger, publisher, schedule, graph))
+		router.Post("/{group}/{name}/refresh", refreshTableHandler(logger, publisher, schedule, graph, metas))
+		router.Post("/dead_letters/{group}/{name}/retry", deadLetterRetryHandler(logger, letters, publisher))
+	})
+	router.Get("/{group}/{name}/events", tableEventsHandler(logger, log))
+	router.Get("/{group}/{name}/meta", metaHandler(logger, metas))
 	router.Get("/{group}/{name}", func(w http.ResponseWriter, r *http.Request) {
 		group := chi.URLParam(r, "group")
 		name := chi.URLParam(r, "name")
@@ -43,11 +68,33 @@ func jsonHandler(logger *lgr.Logger, viewer repo.JSONViewer) http.Handler {
 	return router
 }
 
-func NewHTTPServer(logger *lgr.Logger, db *mongo.Database, addr string, requestTimeouts time.Duration) *server.Server {
+func NewHTTPServer(
+	logger *lgr.Logger,
+	db *mongo.Database,
+	publisher Publisher,
+	graph bus.Graph,
+	schedule end.Schedule,
+	token string,
+	addr string,
+	requestTimeouts time.Duration,
+) *server.Server {
+	viewer := repo.NewMongoJSON(db)
+
 	srv := server.NewServer(
 		logger,
 		addr,
-		jsonHandler(logger, repo.NewMongoJSON(db)),
+		jsonHandler(
+			logger,
+			viewer,
+			viewer,
+			repo.NewDeadLetters(db),
+			repo.NewEventLog(db),
+			repo.NewTableStatuses(db),
+			publisher,
+			graph,
+			schedule,
+			token,
+		),
 		requestTimeouts,
 	)
 
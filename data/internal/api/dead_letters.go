@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"net/http"
+
+	"github.com/go-chi/chi"
+)
+
+// Publisher направляет события из API в шину событий.
+type Publisher interface {
+	Publish(ctx context.Context, event domain.Event) error
+}
+
+// deadLettersListHandler отдает все неудачные обновления таблиц или только обновления цепочки событий, заданной
+// параметром correlation.
+func deadLettersListHandler(logger *lgr.Logger, letters *repo.DeadLetters) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var (
+			list []repo.DeadLetter
+			err  error
+		)
+
+		if correlation := r.URL.Query().Get("correlation"); correlation != "" {
+			list, err = letters.Chain(r.Context(), correlation)
+		} else {
+			list, err = letters.List(r.Context())
+		}
+
+		if err != nil {
+			logger.Warnf("Server: can't get dead letters -> %s", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		if err = json.NewEncoder(w).Encode(list); err != nil {
+			logger.Warnf("Server: can't write respond -> %s", err)
+		}
+	}
+}
+
+// deadLetterRetryHandler публикует запрос на повторное обновление таблицы правилом, которое не смогло ее обновить.
+//
+// Запрос продолжает цепочку событий неудачного обновления.
+func deadLetterRetryHandler(logger *lgr.Logger, letters *repo.DeadLetters, publisher Publisher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := domain.NewID(chi.URLParam(r, "group"), chi.URLParam(r, "name"))
+
+		ctx := r.Context()
+
+		letter, err := letters.Get(ctx, id)
+
+		switch {
+		case errors.Is(err, repo.ErrDeadLetterNotFound):
+			logger.Warnf("Server: can't get dead letter -> %s", err)
+			http.NotFound(w, r)
+
+			return
+		case err != nil:
+			logger.Warnf("Server: can't get dead letter -> %s", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+			return
+		}
+
+		retry := domain.CausedBy(letter.Update(), domain.NewRetryRequested(letter.ID(), letter.Date, letter.Rule))
+
+		if err = publisher.Publish(ctx, retry); err != nil {
+			logger.Warnf("Server: can't request retry -> %s", err)
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
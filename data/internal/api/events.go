@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"net/http"
+	"reflect"
+
+	"github.com/go-chi/chi"
+)
+
+// _tableEvents - количество последних событий таблицы в ответе.
+const _tableEvents = 20
+
+// eventView - представление события из цепочки событий.
+type eventView struct {
+	ID          string `json:"id"`
+	Causation   string `json:"causation,omitempty"`
+	Correlation string `json:"correlation"`
+	Type        string `json:"type"`
+	Event       string `json:"event"`
+}
+
+func newEventViews(events []domain.Event) []eventView {
+	views := make([]eventView, 0, len(events))
+	for _, event := range events {
+		trace := event.Trace()
+		views = append(views, eventView{
+			ID:          trace.ID,
+			Causation:   trace.Causation,
+			Correlation: trace.Correlation,
+			Type:        reflect.TypeOf(event).Name(),
+			Event:       event.String(),
+		})
+	}
+
+	return views
+}
+
+func writeEvents(w http.ResponseWriter, logger *lgr.Logger, events []domain.Event) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	if err := json.NewEncoder(w).Encode(newEventViews(events)); err != nil {
+		logger.Warnf("Server: can't write respond -> %s", err)
+	}
+}
+
+// chainHandler отдает все события цепочки в порядке их возникновения.
+func chainHandler(logger *lgr.Logger, log *repo.EventLog) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		correlation := chi.URLParam(r, "correlation")
+
+		events, err := log.Chain(r.Context(), correlation)
+		if err != nil {
+			logger.Warnf("Server: can't get events chain -> %s", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+			return
+		}
+
+		if len(events) == 0 {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		writeEvents(w, logger, events)
+	}
+}
+
+// tableEventsHandler отдает последние события таблицы, начиная с самых новых.
+//
+// События о начале, пропуске, завершении и ошибках обновления позволяют определить текущее состояние таблицы.
+func tableEventsHandler(logger *lgr.Logger, log *repo.EventLog) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := domain.NewID(chi.URLParam(r, "group"), chi.URLParam(r, "name"))
+
+		events, err := log.Table(r.Context(), id, _tableEvents)
+		if err != nil {
+			logger.Warnf("Server: can't get table events -> %s", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+			return
+		}
+
+		writeEvents(w, logger, events)
+	}
+}
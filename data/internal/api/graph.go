@@ -0,0 +1,32 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/WLM1ke/poptimizer/data/internal/bus"
+	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"net/http"
+)
+
+// graphHandler отдает граф зависимостей правил в формате JSON или Graphviz DOT при указании format=dot.
+func graphHandler(logger *lgr.Logger, graph bus.Graph) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var err error
+
+		switch r.URL.Query().Get("format") {
+		case "dot":
+			w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+			_, err = w.Write(graph.DOT())
+		case "", "json":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			err = json.NewEncoder(w).Encode(graph)
+		default:
+			http.Error(w, "unknown graph format", http.StatusBadRequest)
+
+			return
+		}
+
+		if err != nil {
+			logger.Warnf("Server: can't write respond -> %s", err)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+// metaView - служебные сведения о таблице, позволяющие определить изменение данных без загрузки таблицы.
+type metaView struct {
+	Date    time.Time `json:"date"`
+	Updated time.Time `json:"updated"`
+	Source  string    `json:"source"`
+	Schema  int       `json:"schema"`
+	Rows    int       `json:"rows"`
+	Hash    string    `json:"hash"`
+}
+
+// metaHandler отдает служебные сведения о таблице.
+func metaHandler(logger *lgr.Logger, viewer repo.MetaViewer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := domain.NewID(chi.URLParam(r, "group"), chi.URLParam(r, "name"))
+
+		date, meta, err := viewer.GetMeta(r.Context(), id)
+
+		switch {
+		case errors.Is(err, repo.ErrTableNotFound):
+			logger.Warnf("Server: can't get table meta -> %s", err)
+			http.NotFound(w, r)
+
+			return
+		case err != nil:
+			logger.Warnf("Server: can't get table meta -> %s", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+			return
+		}
+
+		view := metaView{
+			Date:    date,
+			Updated: meta.Updated,
+			Source:  meta.Source,
+			Schema:  meta.Schema,
+			Rows:    meta.Rows,
+			Hash:    meta.Hash,
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		if err = json.NewEncoder(w).Encode(view); err != nil {
+			logger.Warnf("Server: can't write respond -> %s", err)
+		}
+	}
+}
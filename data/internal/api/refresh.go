@@ -0,0 +1,136 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"github.com/WLM1ke/poptimizer/data/internal/bus"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/end"
+	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+const _bearerPrefix = "Bearer "
+
+// refreshRespond - ответ на запрос принудительного обновления.
+type refreshRespond struct {
+	CorrelationID string `json:"correlation_id"`
+}
+
+// authenticated пропускает только запросы с заголовком Authorization: Bearer <token>.
+//
+// Если токен не задан, запросы изменяющие состояние сервиса запрещены.
+func authenticated(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got := strings.TrimPrefix(r.Header.Get("Authorization"), _bearerPrefix)
+
+			if token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// refreshTableHandler запрашивает повторное обновление таблицы обновляющим ее правилом, чтобы таблица и зависящие от
+// нее таблицы обновились немедленно.
+//
+// Запрос датируется последним торговым днем, итоги которого должны быть опубликованы по расписанию. Для таблиц,
+// которые не обновляются ни одним правилом, и еще не сохраненных таблиц групп возвращается 404.
+func refreshTableHandler(
+	logger *lgr.Logger,
+	publisher Publisher,
+	schedule end.Schedule,
+	graph bus.Graph,
+	metas repo.MetaViewer,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := domain.NewID(chi.URLParam(r, "group"), chi.URLParam(r, "name"))
+
+		rule := graph.Producer(id)
+		if rule == "" {
+			logger.Warnf("Server: can't refresh table without rule %s", id)
+			http.NotFound(w, r)
+
+			return
+		}
+
+		if !graph.Declared(id) {
+			_, _, err := metas.GetMeta(r.Context(), id)
+
+			switch {
+			case errors.Is(err, repo.ErrTableNotFound):
+				logger.Warnf("Server: can't refresh unknown table -> %s", err)
+				http.NotFound(w, r)
+
+				return
+			case err != nil:
+				logger.Warnf("Server: can't get table meta -> %s", err)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+				return
+			}
+		}
+
+		publishRefresh(w, r, logger, publisher, schedule, id, rule)
+	}
+}
+
+// refreshPipelineHandler запрашивает повторное сообщение о начале нового дня, что запускает обновление всех таблиц,
+// зависящих от итогов торгов.
+func refreshPipelineHandler(
+	logger *lgr.Logger,
+	publisher Publisher,
+	schedule end.Schedule,
+	graph bus.Graph,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		publishRefresh(w, r, logger, publisher, schedule, end.ID, graph.Producer(end.ID))
+	}
+}
+
+func publishRefresh(
+	w http.ResponseWriter,
+	r *http.Request,
+	logger *lgr.Logger,
+	publisher Publisher,
+	schedule end.Schedule,
+	id domain.ID,
+	rule string,
+) {
+	date, err := schedule.Last(r.Context(), time.Now())
+	if err != nil {
+		logger.Warnf("Server: can't get last trading day -> %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+		return
+	}
+
+	event := domain.NewRetryRequested(id, date, rule)
+
+	if err := publisher.Publish(r.Context(), event); err != nil {
+		logger.Warnf("Server: can't request refresh -> %s", err)
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+
+		return
+	}
+
+	logger.Infof("Server: refresh %s requested %s", event, event.Trace())
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusAccepted)
+
+	if err := json.NewEncoder(w).Encode(refreshRespond{CorrelationID: event.Trace().Correlation}); err != nil {
+		logger.Warnf("Server: can't write respond -> %s", err)
+	}
+}
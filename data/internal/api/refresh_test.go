@@ -0,0 +1,157 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/WLM1ke/poptimizer/data/internal/bus"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/end"
+	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"github.com/stretchr/testify/assert"
+)
+
+const _testToken = "secret"
+
+var _lastTradingDay = time.Date(2021, time.December, 30, 0, 0, 0, 0, time.UTC)
+
+type fakePublisher struct {
+	events []domain.Event
+	err    error
+}
+
+func (f *fakePublisher) Publish(_ context.Context, event domain.Event) error {
+	if f.err != nil {
+		return f.err
+	}
+
+	f.events = append(f.events, event)
+
+	return nil
+}
+
+type fakeSchedule struct{}
+
+func (f fakeSchedule) ID() domain.ID {
+	return end.ID
+}
+
+func (f fakeSchedule) Last(_ context.Context, _ time.Time) (time.Time, error) {
+	return _lastTradingDay, nil
+}
+
+// fakeMetas - хранилище служебных сведений с единственной сохраненной таблицей quotes/AKRN.
+type fakeMetas struct{}
+
+func (f fakeMetas) GetMeta(_ context.Context, id domain.ID) (time.Time, domain.TableMeta, error) {
+	if id != domain.NewID("quotes", "AKRN") {
+		return time.Time{}, domain.TableMeta{}, fmt.Errorf("%w: %#v", repo.ErrTableNotFound, id)
+	}
+
+	return _lastTradingDay, domain.TableMeta{}, nil
+}
+
+var _testGraph = bus.Graph{
+	Rules: []bus.GraphRule{
+		{Name: "DayEndedRule", Produces: []string{end.ID.String()}},
+		{Name: "USDRule", Produces: []string{"usd/USD"}},
+		{Name: "QuotesRule", Produces: []string{"quotes/*"}},
+	},
+}
+
+func newTestHandler(publisher Publisher, token string) http.Handler {
+	return jsonHandler(lgr.NoOp(), nil, fakeMetas{}, nil, nil, nil, publisher, _testGraph, fakeSchedule{}, token)
+}
+
+func refresh(handler http.Handler, path, auth string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, http.NoBody)
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestAuthenticated(t *testing.T) {
+	tbl := []struct {
+		name   string
+		token  string
+		auth   string
+		status int
+	}{
+		{"no token configured", "", "Bearer ", http.StatusUnauthorized},
+		{"no header", _testToken, "", http.StatusUnauthorized},
+		{"wrong token", _testToken, "Bearer wrong", http.StatusUnauthorized},
+		{"no bearer prefix", _testToken, "Basic " + _testToken, http.StatusUnauthorized},
+		{"valid token", _testToken, "Bearer " + _testToken, http.StatusAccepted},
+	}
+
+	for _, testCase := range tbl {
+		publisher := fakePublisher{}
+		rec := refresh(newTestHandler(&publisher, testCase.token), "/refresh", testCase.auth)
+
+		assert.Equal(t, testCase.status, rec.Code, testCase.name)
+
+		if testCase.status != http.StatusAccepted {
+			assert.Empty(t, publisher.events, testCase.name)
+		}
+	}
+}
+
+func TestRefreshHandlers(t *testing.T) {
+	tbl := []struct {
+		path string
+		id   domain.ID
+		rule string
+	}{
+		{"/refresh", end.ID, "DayEndedRule"},
+		{"/usd/USD/refresh", domain.NewID("usd", "USD"), "USDRule"},
+		{"/quotes/AKRN/refresh", domain.NewID("quotes", "AKRN"), "QuotesRule"},
+	}
+
+	for _, testCase := range tbl {
+		publisher := fakePublisher{}
+		rec := refresh(newTestHandler(&publisher, _testToken), testCase.path, "Bearer "+_testToken)
+
+		assert.Equal(t, http.StatusAccepted, rec.Code, testCase.path)
+		assert.Len(t, publisher.events, 1, testCase.path)
+
+		event, ok := publisher.events[0].(domain.RetryRequested)
+		assert.True(t, ok, testCase.path)
+		assert.Equal(t, testCase.id, event.ID(), testCase.path)
+		assert.Equal(t, testCase.rule, event.Rule(), testCase.path)
+		assert.Equal(t, _lastTradingDay, event.Date(), testCase.path)
+
+		var respond refreshRespond
+
+		assert.Nil(t, json.NewDecoder(rec.Body).Decode(&respond), testCase.path)
+		assert.Equal(t, event.Trace().Correlation, respond.CorrelationID, testCase.path)
+	}
+}
+
+func TestRefreshUnknownTable(t *testing.T) {
+	for _, path := range []string{"/unknown/AKRN/refresh", "/usd/EUR/refresh", "/quotes/AKRM/refresh"} {
+		publisher := fakePublisher{}
+		rec := refresh(newTestHandler(&publisher, _testToken), path, "Bearer "+_testToken)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code, path)
+		assert.Empty(t, publisher.events, path)
+	}
+}
+
+func TestRefreshBusStopped(t *testing.T) {
+	publisher := fakePublisher{err: errors.New("stopped")}
+	rec := refresh(newTestHandler(&publisher, _testToken), "/refresh", "Bearer "+_testToken)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
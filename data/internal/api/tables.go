@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/WLM1ke/poptimizer/data/internal/bus"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/tables"
+	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"net/http"
+)
+
+// tableStatusView - состояние обновления таблицы вместе с обновляющим ее правилом.
+type tableStatusView struct {
+	repo.TableStatus
+	Rule string `json:"rule"`
+}
+
+// tableStatuses - хранилище состояния обновления таблиц.
+type tableStatuses interface {
+	List(ctx context.Context, seed []domain.ID) ([]repo.TableStatus, error)
+}
+
+// tablesHandler отдает состояние обновления всех таблиц, позволяющее найти устаревшие и ни разу не обновлявшиеся
+// таблицы.
+func tablesHandler(logger *lgr.Logger, statuses tableStatuses, graph bus.Graph) http.HandlerFunc {
+	seed := make([]domain.ID, 0, len(graph.Tables()))
+
+	for _, id := range graph.Tables() {
+		if tables.Stored(id) {
+			seed = append(seed, id)
+		}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		list, err := statuses.List(r.Context(), seed)
+		if err != nil {
+			logger.Warnf("Server: can't get table statuses -> %s", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+			return
+		}
+
+		views := make([]tableStatusView, 0, len(list))
+
+		for _, status := range list {
+			if tables.Stored(status.ID()) {
+				views = append(views, tableStatusView{TableStatus: status, Rule: graph.Producer(status.ID())})
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		if err = json.NewEncoder(w).Encode(views); err != nil {
+			logger.Warnf("Server: can't write respond -> %s", err)
+		}
+	}
+}
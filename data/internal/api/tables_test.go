@@ -0,0 +1,44 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/WLM1ke/poptimizer/data/internal/bus"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeTableStatuses struct {
+	statuses []repo.TableStatus
+}
+
+func (f fakeTableStatuses) List(_ context.Context, _ []domain.ID) ([]repo.TableStatus, error) {
+	return f.statuses, nil
+}
+
+func TestTablesHandler(t *testing.T) {
+	statuses := fakeTableStatuses{statuses: []repo.TableStatus{
+		{Group: "day_ended", Name: "day_ended"},
+		{Group: "quotes", Name: "AKRN"},
+		{Group: "source", Name: "MOEX ISS"},
+	}}
+	graph := bus.Graph{Rules: []bus.GraphRule{{Name: "QuotesRule", Produces: []string{"quotes/*"}}}}
+
+	rec := httptest.NewRecorder()
+	tablesHandler(lgr.NoOp(), statuses, graph).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tables", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var views []tableStatusView
+
+	assert.Nil(t, json.NewDecoder(rec.Body).Decode(&views))
+	assert.Equal(t, []tableStatusView{
+		{TableStatus: repo.TableStatus{Group: "quotes", Name: "AKRN"}, Rule: "QuotesRule"},
+	}, views, "Некорректный список таблиц")
+}
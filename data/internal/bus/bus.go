@@ -4,19 +4,31 @@ package bus
 import (
 	"context"
 	"fmt"
-	"github.com/WLM1ke/gomoex"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/cbr"
 	"github.com/WLM1ke/poptimizer/data/internal/rules/cpi"
 	"github.com/WLM1ke/poptimizer/data/internal/rules/dates"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/dividends"
 	"github.com/WLM1ke/poptimizer/data/internal/rules/end"
 	"github.com/WLM1ke/poptimizer/data/internal/rules/errors"
 	"github.com/WLM1ke/poptimizer/data/internal/rules/indexes"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/liquidity"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/listing"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/quality"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/quotes"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/recovery"
 	"github.com/WLM1ke/poptimizer/data/internal/rules/securities"
 	"github.com/WLM1ke/poptimizer/data/internal/rules/status"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/tables"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/totalreturn"
 	"github.com/WLM1ke/poptimizer/data/internal/rules/usd"
 	"github.com/WLM1ke/poptimizer/data/pkg/client"
 	"go.mongodb.org/mongo-driver/mongo"
 	"net/http"
+	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/WLM1ke/poptimizer/data/internal/domain"
@@ -26,124 +38,456 @@ import (
 // errUnprocessedEvent ошибка связанная с наличием необработанных ошибок в момент завершения работы шины событий.
 var errUnprocessedEvent = fmt.Errorf("unprocessed event")
 
+// errRulesRunning ошибка, связанная с правилами, не завершившими работу за отведенное на остановку время.
+var errRulesRunning = fmt.Errorf("rules still running")
+
+// errBusStopped ошибка публикации события в остановленную шину событий.
+var errBusStopped = fmt.Errorf("event bus stopped")
+
 // EventBus осуществляет перенаправление исходящих событий правилам по их обработке.
+//
+// События направляются только правилам, подписанным на них, поэтому скорость рассылки зависит от количества
+// получателей события, а не от общего количества правил. Каждое правило получает события через собственную
+// неограниченную очередь, поэтому медленное правило не блокирует рассылку остальным.
+//
+// Все события сохраняются в журнал и отмечаются обработанными после их обработки всеми получателями. Необработанные
+// события повторно рассылаются после перезапуска, что позволяет завершить прерванные цепочки обновлений.
+//
+// Остановка происходит в два этапа. Сначала шина перестает принимать события, начинающие новые цепочки, и сохраняет
+// их для обработки после перезапуска. Затем правила останавливаются в порядке зависимостей, чтобы выполняющиеся
+// обновления и вызванные ими обновления зависимых таблиц успели завершиться. По истечении срока остановки контекст
+// правил отменяется, а шина останавливается без ожидания незавершенных правил - недоставленные и необработанные ими
+// события остаются в журнале.
 type EventBus struct {
-	logger *lgr.Logger
-	rules  []domain.Rule
+	logger  *lgr.Logger
+	rules   []domain.Rule
+	graph   Graph
+	journal *journal
+	// schedule расписание публикации итогов торгов
+	schedule end.Schedule
+	// deadLetters хранилище неудачных обновлений правил
+	deadLetters *repo.DeadLetters
+	// drainTimeout ограничение времени на завершение выполняющихся обновлений при остановке
+	drainTimeout time.Duration
+	// cancelRules отменяет контекст правил по истечении срока остановки
+	cancelRules context.CancelFunc
 
 	// inbox канал в который правила записывают новые события
 	inbox chan domain.Event
 	// broadcast канал в который направляются события из inbox для рассылки в каналы отдельных правил
-	broadcast chan domain.Event
-	// consumers входные каналы правил, в которые дублируются события из broadcast
-	consumers []chan domain.Event
+	broadcast chan *envelope
+	// consumers входные очереди правил, в которые направляются события из broadcast
+	consumers []chan *envelope
+	// routes очереди правил, подписанных на события с данным ключом
+	routes map[domain.Subscription][]chan *envelope
+	// everything очереди правил без подписок, получающих все события
+	everything []chan *envelope
+	// names названия правил по их очередям
+	names map[chan *envelope]string
+	// finished номера правил, завершивших работу
+	finished chan int
+	// abort закрывается по истечении срока остановки, после чего накопленные в очередях события не рассылаются
+	abort chan struct{}
+	// undelivered количество событий, не переданных части получателей из-за остановки
+	undelivered int32
+	// abandoned количество правил, не завершивших работу за отведенное на остановку время
+	abandoned int
+
+	// lock защищает inbox от публикации внешних событий после закрытия
+	lock    sync.RWMutex
+	stopped bool
 
 	wg sync.WaitGroup
 }
 
 // NewEventBus создает шину событий со всеми правилами обработки событий.
+//
+// Проверяет, что зависимости правил через таблицы не содержат циклов и таблиц, которые никто не обновляет.
 func NewEventBus(
 	logger *lgr.Logger,
 	db *mongo.Database,
 	client *http.Client,
 	telegram *client.Telegram,
-	timeout time.Duration,
-) *EventBus {
-	iss := gomoex.NewISSClient(client)
+	cfg Config,
+) (*EventBus, error) {
+	limits, err := template.ParseConcurrency(cfg.RuleConcurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	calendar, err := indexes.NewCalendar(db, cfg.Schedule.Holidays, cfg.Schedule.Workdays)
+	if err != nil {
+		return nil, err
+	}
+
+	currency, err := indexes.NewCurrencyCalendar(cfg.CurrencyHolidays, cfg.CurrencyWorkdays)
+	if err != nil {
+		return nil, err
+	}
+
+	schedules, err := end.NewSchedules(cfg.Schedule, calendar)
+	if err != nil {
+		return nil, err
+	}
+
+	// Контекст правил отменяется, если они не успели завершить работу за отведенное на остановку время
+	rulesCtx, cancel := context.WithCancel(context.Background())
+	eventCtx := template.EventCtxFuncWithTimeout(rulesCtx, cfg.Timeout)
+
+	liquidityRule, err := liquidity.New(logger, db, cfg.LiquidityWindows, eventCtx)
+	if err != nil {
+		cancel()
+
+		return nil, err
+	}
+
+	iss := template.NewISSClient(client)
+	deadLetters := repo.NewDeadLetters(db)
+	opts := template.Options{
+		Context:         rulesCtx,
+		Timeout:         cfg.Timeout,
+		Retry:           cfg.Retry,
+		DeadLetters:     deadLetters,
+		Concurrency:     cfg.Concurrency,
+		RuleConcurrency: limits,
+		Breakers:        cfg.Breakers,
+	}
+
+	indexListRule, err := indexes.NewList(logger, db, cfg.IndexList, opts)
+	if err != nil {
+		cancel()
+
+		return nil, err
+	}
 
 	rules := []domain.Rule{
-		errors.New(logger, telegram, timeout),
-		end.New(logger),
-		dates.New(logger, db, iss, timeout),
-		usd.New(logger, db, iss, timeout),
-		cpi.New(logger, db, client, timeout),
-		securities.New(logger, db, iss, timeout),
-		status.New(logger, db, client, timeout),
-		indexes.New(logger, db, iss, timeout),
+		errors.New(logger, telegram, eventCtx),
+		tables.New(logger, db, eventCtx),
+		recovery.New(logger, db, eventCtx),
+		end.New(logger, end.SystemClock(), eventCtx, schedules...),
+		dates.New(logger, db, iss, opts),
+		usd.New(logger, db, iss, opts),
+		cbr.New(logger, db, client, opts),
+		cbr.NewCheck(logger, db, cfg.USDTolerance, eventCtx),
+		cpi.New(logger, db, client, opts),
+		securities.New(logger, db, iss, opts),
+		listing.New(logger, db, eventCtx),
+		status.New(logger, db, client, opts),
+		status.NewSmartLab(logger, db, client, opts),
+		status.NewReestry(logger, db, client, opts),
+		status.NewReconcile(logger, db, eventCtx),
+		indexListRule,
+		indexes.New(logger, db, iss, opts),
+		quotes.New(logger, db, iss, opts),
+		dividends.New(logger, db, iss, opts),
+		liquidityRule,
+		quality.New(logger, db, cfg.QualitySigma, calendar, currency, eventCtx),
+		totalreturn.New(logger, db, opts),
+	}
+
+	graph, err := newGraph(rules)
+	if err != nil {
+		cancel()
+
+		return nil, err
 	}
 
 	return &EventBus{
-		logger:    logger,
-		rules:     rules,
+		logger:       logger,
+		rules:        rules,
+		graph:        graph,
+		schedule:     schedules[0],
+		deadLetters:  deadLetters,
+		drainTimeout: cfg.DrainTimeout,
+		cancelRules:  cancel,
+		journal: &journal{
+			logger:  logger,
+			log:     repo.NewEventLog(db),
+			timeout: cfg.Timeout,
+			ttl:     cfg.EventsTTL,
+		},
 		inbox:     make(chan domain.Event),
-		broadcast: make(chan domain.Event),
-	}
+		broadcast: make(chan *envelope),
+		routes:    make(map[domain.Subscription][]chan *envelope),
+	}, nil
+}
+
+// Graph - граф зависимостей правил шины событий.
+func (b *EventBus) Graph() Graph {
+	return b.graph
+}
+
+// Schedule - расписание публикации итогов торгов, по которому правила шины определяют дату последних данных.
+func (b *EventBus) Schedule() end.Schedule {
+	return b.schedule
 }
 
 // Run запускает шину событий.
 func (b *EventBus) Run(ctx context.Context) error {
+	if b.cancelRules != nil {
+		defer b.cancelRules()
+	}
+
 	b.activateConsumers()
 
-	b.wg.Add(1)
+	broadcasted := make(chan struct{})
 
 	go func() {
-		defer b.wg.Done()
+		defer close(broadcasted)
 
 		b.broadcastToConsumers()
 	}()
 
+	b.journal.prepare()
+	b.prepareDeadLetters()
+	b.replayUnprocessed()
 	b.formInboxToBroadcast(ctx)
 
-	if count := b.drainUnprocessedEvents(b.inbox); count != 0 {
+	<-broadcasted
+
+	if b.abandoned != 0 {
+		return fmt.Errorf("%w: count %d", errRulesRunning, b.abandoned)
+	}
+
+	if count := atomic.LoadInt32(&b.undelivered); count != 0 {
 		return fmt.Errorf("%w: count %d", errUnprocessedEvent, count)
 	}
 
 	return nil
 }
 
+// prepareDeadLetters создает индексы хранилища неудачных обновлений.
+func (b *EventBus) prepareDeadLetters() {
+	if b.deadLetters == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), b.journal.timeout)
+	defer cancel()
+
+	if err := b.deadLetters.EnsureIndexes(ctx); err != nil {
+		b.logger.Warnf("EventBus: can't prepare dead letters -> %s", err)
+	}
+}
+
+// Publish направляет в шину событие из внешнего источника, например, запрос на повторное обновление таблицы.
+func (b *EventBus) Publish(ctx context.Context, event domain.Event) error {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	if b.stopped {
+		return fmt.Errorf("%w: %s", errBusStopped, event)
+	}
+
+	select {
+	case b.inbox <- event:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %s -> %s", errBusStopped, event, ctx.Err())
+	}
+}
+
 func (b *EventBus) activateConsumers() {
-	for _, rule := range b.rules {
+	b.finished = make(chan int, len(b.rules))
+	b.abort = make(chan struct{})
+	b.names = make(map[chan *envelope]string, len(b.rules))
+
+	for n, rule := range b.rules {
+		n := n
 		rule := rule
 		consumer := make(chan domain.Event)
-		b.consumers = append(b.consumers, consumer)
+		queue := make(chan *envelope)
+		handling := newHandling(b.journal, rule)
+		b.consumers = append(b.consumers, queue)
+		b.names[queue] = ruleName(rule)
+		b.subscribe(rule, queue)
 
-		b.wg.Add(1)
+		b.wg.Add(2)
 
 		go func() {
 			defer b.wg.Done()
 
-			rule.Activate(consumer, b.inbox)
+			b.forwardWithQueue(queue, consumer, handling)
+		}()
+
+		go func() {
+			defer b.wg.Done()
+
+			if acknowledger, ok := rule.(domain.Acknowledger); ok {
+				acknowledger.ActivateWithAck(consumer, b.inbox, handling.ack)
+			} else {
+				rule.Activate(consumer, b.inbox)
+			}
+
+			handling.finished()
+			b.finished <- n
 		}()
 	}
 }
 
+func (b *EventBus) subscribe(rule domain.Rule, queue chan *envelope) {
+	subscriber, ok := rule.(domain.Subscriber)
+	if !ok {
+		b.everything = append(b.everything, queue)
+
+		return
+	}
+
+	for _, sub := range subscriber.Subscriptions() {
+		b.routes[sub] = append(b.routes[sub], queue)
+	}
+}
+
 func (b *EventBus) broadcastToConsumers() {
-	for event := range b.broadcast {
-		for _, consumer := range b.consumers {
-			consumer <- event
+	stopped := make(map[chan *envelope]bool)
+
+	for env := range b.broadcast {
+		if env.stop != nil {
+			stopped[env.stop] = true
+			close(env.stop)
+
+			continue
+		}
+
+		var targets []chan *envelope
+
+		sent := make(map[chan *envelope]bool)
+
+		for _, consumer := range b.everything {
+			if !env.handled[b.names[consumer]] {
+				targets = append(targets, consumer)
+			}
+		}
+
+		for _, key := range domain.Keys(env.event) {
+			for _, consumer := range b.routes[key] {
+				// Повторно рассылаемое событие не передается правилам, обработавшим его до остановки сервиса
+				if !sent[consumer] && !env.handled[b.names[consumer]] {
+					sent[consumer] = true
+					targets = append(targets, consumer)
+				}
+			}
+		}
+
+		b.journal.route(env, len(targets))
+
+		skipped := 0
+
+		for _, consumer := range targets {
+			if stopped[consumer] {
+				skipped++
+
+				continue
+			}
+
+			consumer <- env
+		}
+
+		if skipped != 0 {
+			b.logger.Warnf("EventBus: event %s not delivered to %d stopped rule(s), saved for replay", env.event, skipped)
+			atomic.AddInt32(&b.undelivered, 1)
 		}
 	}
 
 	for _, consumer := range b.consumers {
-		close(consumer)
+		if !stopped[consumer] {
+			close(consumer)
+		}
 	}
 }
 
-func (b *EventBus) formInboxToBroadcast(ctx context.Context) {
-	for {
+// forwardWithQueue пересылает события в правило, накапливая их в очереди, пока правило занято.
+//
+// После закрытия входящего канала пересылает накопленные события и закрывает исходящий. Если срок остановки истек,
+// накопленные события не пересылаются и остаются необработанными в журнале.
+func (b *EventBus) forwardWithQueue(in <-chan *envelope, out chan<- domain.Event, handling *handling) {
+	defer close(out)
+
+	var queue []*envelope
+
+	for in != nil || len(queue) > 0 {
+		var (
+			next  domain.Event
+			send  chan<- domain.Event
+			abort <-chan struct{}
+		)
+
+		if len(queue) > 0 {
+			next = queue[0].event
+			send = out
+
+			handling.expect(queue[0])
+		}
+
+		if in == nil {
+			abort = b.abort
+		}
+
 		select {
-		case <-ctx.Done():
-			close(b.broadcast)
+		case <-abort:
+			b.logger.Warnf("EventBus: %d queued event(s) not delivered, saved for replay", len(queue))
+			atomic.AddInt32(&b.undelivered, int32(len(queue)))
 
 			return
-		case event := <-b.inbox:
-			b.logger.Infof("EventBus: processing event %s", event)
-			b.broadcast <- event
+		case env, ok := <-in:
+			if !ok {
+				in = nil
+
+				continue
+			}
+
+			queue = append(queue, env)
+		case send <- next:
+			handling.sent(queue[0])
+
+			queue[0] = nil
+			queue = queue[1:]
 		}
 	}
 }
 
-func (b *EventBus) drainUnprocessedEvents(inbox <-chan domain.Event) (count int) {
-	go func() {
-		b.wg.Wait()
-		close(b.inbox)
-	}()
-
-	for event := range inbox {
-		b.logger.Warnf(
-			"EventBus: unprocessed event %s", event)
-		count++
+func (b *EventBus) replayUnprocessed() {
+	for _, env := range b.journal.unprocessed() {
+		b.logger.Infof("EventBus: replaying event %s %s", env.event, env.event.Trace())
+		b.broadcast <- env
 	}
+}
+
+// formInboxToBroadcast рассылает события до завершения работы всех правил.
+//
+// После отмены контекста события, начинающие новые цепочки, сохраняются для обработки после перезапуска, а в
+// рассылку направляются запросы на остановку правил.
+func (b *EventBus) formInboxToBroadcast(ctx context.Context) {
+	var (
+		done  = ctx.Done()
+		stops chan chan *envelope
+	)
+
+	for {
+		select {
+		case <-done:
+			done = nil
+			stops = make(chan chan *envelope)
+
+			go b.stopConsumers(stops)
+		case queue, ok := <-stops:
+			if !ok {
+				close(b.broadcast)
+
+				return
+			}
 
-	return count
+			b.broadcast <- &envelope{stop: queue}
+		case event := <-b.inbox:
+			switch {
+			case stops != nil && event.Trace().Causation == "":
+				b.logger.Infof("EventBus: new chain %s %s postponed until restart", event, event.Trace())
+				b.journal.save(event)
+			default:
+				b.logger.Infof("EventBus: processing event %s %s", event, event.Trace())
+				eventsTotal.WithLabelValues(reflect.TypeOf(event).Name()).Inc()
+				b.broadcast <- b.journal.save(event)
+			}
+		}
+	}
 }
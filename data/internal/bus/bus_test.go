@@ -0,0 +1,83 @@
+package bus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeRule struct {
+	subs []domain.Subscription
+	got  chan domain.Event
+}
+
+func (f fakeRule) Subscriptions() []domain.Subscription {
+	return f.subs
+}
+
+func (f fakeRule) Activate(in <-chan domain.Event, _ chan<- domain.Event) {
+	for event := range in {
+		f.got <- event
+	}
+
+	close(f.got)
+}
+
+func TestRouting(t *testing.T) {
+	quotes := domain.NewID("quotes", "AKRN")
+	other := domain.NewID("quotes", "GAZP")
+	date := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	byID := fakeRule{
+		subs: []domain.Subscription{domain.Subscribe[domain.UpdateCompleted](quotes)},
+		got:  make(chan domain.Event, 10),
+	}
+	byGroup := fakeRule{
+		subs: []domain.Subscription{
+			domain.SubscribeGroup[domain.UpdateCompleted]("quotes"),
+			domain.Subscribe[domain.UpdateCompleted](quotes),
+		},
+		got: make(chan domain.Event, 10),
+	}
+	errs := fakeRule{
+		subs: []domain.Subscription{domain.SubscribeAll[domain.ErrorOccurred]()},
+		got:  make(chan domain.Event, 10),
+	}
+
+	bus := EventBus{
+		rules:     []domain.Rule{byID, byGroup, errs},
+		inbox:     make(chan domain.Event),
+		broadcast: make(chan *envelope),
+		routes:    make(map[domain.Subscription][]chan *envelope),
+	}
+
+	bus.activateConsumers()
+
+	go bus.broadcastToConsumers()
+
+	events := []domain.Event{
+		domain.NewUpdateCompleted(quotes, date),
+		domain.NewUpdateCompleted(other, date),
+		domain.NewErrorOccurred(domain.NewUpdateCompleted(other, date), nil),
+	}
+	for _, event := range events {
+		bus.broadcast <- bus.journal.save(event)
+	}
+
+	close(bus.broadcast)
+	bus.wg.Wait()
+
+	collect := func(rule fakeRule) (got []domain.Event) {
+		for event := range rule.got {
+			got = append(got, event)
+		}
+
+		return got
+	}
+
+	assert.Equal(t, events[:1], collect(byID), "Некорректные события для подписки на таблицу")
+	assert.Equal(t, events[:2], collect(byGroup), "Некорректные события для подписки на группу")
+	assert.Equal(t, events[2:], collect(errs), "Некорректные события для подписки на тип")
+}
@@ -0,0 +1,39 @@
+package bus
+
+import (
+	"github.com/WLM1ke/poptimizer/data/internal/rules/end"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
+	"time"
+)
+
+// Config - настройки шины событий и ее правил.
+type Config struct {
+	// Timeout - ограничение времени на обработку события правилом.
+	Timeout time.Duration
+	// DrainTimeout - ограничение времени на завершение выполняющихся обновлений при остановке.
+	DrainTimeout time.Duration
+	// EventsTTL - срок хранения обработанных событий в журнале.
+	EventsTTL time.Duration
+	// USDTolerance - допустимое относительное расхождение курса ЦБ РФ и биржевого курса доллара.
+	USDTolerance float64
+	// LiquidityWindows - окна в торговых днях для расчета медианного оборота.
+	LiquidityWindows []int
+	// QualitySigma - количество стандартных отклонений дневного изменения, начиная с которого оно считается выбросом.
+	QualitySigma float64
+	// IndexList - загружаемые индексы в формате TICKER или TICKER:market.
+	IndexList []string
+	// Retry - настройки повторных попыток при временных ошибках.
+	Retry template.Retry
+	// Concurrency - максимальное количество одновременно обновляемых правилом таблиц.
+	Concurrency int
+	// RuleConcurrency - ограничения для отдельных правил в формате Rule:limit.
+	RuleConcurrency []string
+	// Breakers - общие для всех правил предохранители внешних источников данных. Nil отключает предохранители.
+	Breakers *template.Breakers
+	// Schedule - расписание публикации данных и торговый календарь.
+	Schedule end.Settings
+	// CurrencyHolidays - праздничные дни валютного рынка MOEX в формате 2006-01-02 в дополнение к выходным.
+	CurrencyHolidays []string
+	// CurrencyWorkdays - выходные дни, объявленные рабочими на валютном рынке MOEX, в формате 2006-01-02.
+	CurrencyWorkdays []string
+}
@@ -0,0 +1,163 @@
+package bus
+
+import (
+	"fmt"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"strings"
+	"time"
+)
+
+// stopConsumers останавливает правила в порядке зависимостей и закрывает канал запросов на остановку после
+// завершения их работы.
+//
+// Очередь правила закрывается после завершения работы всех правил, которые могут порождать для него события, поэтому
+// правило успевает обработать все вызванные ими обновления. По истечении срока остановки оставшиеся правила
+// останавливаются без ожидания, а их контекст отменяется. Незавершенные правила записываются в лог, а их
+// неподтвержденные события остаются в журнале для обработки после перезапуска.
+func (b *EventBus) stopConsumers(stops chan<- chan *envelope) {
+	defer close(stops)
+
+	b.lock.Lock()
+	b.stopped = true
+	b.lock.Unlock()
+
+	b.logger.Infof("EventBus: draining in-flight updates")
+
+	deadline := time.NewTimer(b.drainTimeout)
+	defer deadline.Stop()
+
+	var (
+		parents   = upstreams(b.rules)
+		finished  = make([]bool, len(b.rules))
+		requested = make([]bool, len(b.rules))
+		expired   = false
+		running   = len(b.rules)
+	)
+
+	expire := func() {
+		expired = true
+		close(b.abort)
+		b.logger.Warnf("EventBus: drain timeout %s exceeded", b.drainTimeout)
+
+		if b.cancelRules != nil {
+			b.cancelRules()
+		}
+	}
+
+	for left := len(b.rules); running > 0; {
+		for n := range b.rules {
+			if requested[n] || !(expired || allFinished(parents[n], finished)) {
+				continue
+			}
+
+			requested[n] = true
+			left--
+			stops <- b.consumers[n]
+		}
+
+		if expired && left == 0 {
+			break
+		}
+
+		select {
+		case n := <-b.finished:
+			finished[n] = true
+			running--
+		case <-deadline.C:
+			expire()
+		}
+	}
+
+	if running != 0 {
+		b.abandoned = running
+		b.logger.Warnf("EventBus: rules still running after drain timeout %s", strings.Join(b.running(finished), ", "))
+
+		return
+	}
+
+	b.wg.Wait()
+}
+
+// running - названия правил, не завершивших работу.
+func (b *EventBus) running(finished []bool) (names []string) {
+	for n, rule := range b.rules {
+		if finished[n] {
+			continue
+		}
+
+		if node, ok := rule.(domain.Node); ok {
+			names = append(names, node.Name())
+
+			continue
+		}
+
+		names = append(names, fmt.Sprintf("%T", rule))
+	}
+
+	return names
+}
+
+func allFinished(rules []int, finished []bool) bool {
+	for _, n := range rules {
+		if !finished[n] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// upstreams - номера правил, которые могут порождать события для каждого из правил.
+//
+// Правило зависит от правил, обновляющих используемые им таблицы. Правила, которые не обновляют таблиц, например,
+// рассылающие уведомления об ошибках, могут получать события от любых правил и зависят от всех правил,
+// обновляющих таблицы.
+func upstreams(rules []domain.Rule) [][]int {
+	nodes := make([]domain.Node, len(rules))
+	producers := make([]int, 0, len(rules))
+
+	for n, rule := range rules {
+		if node, ok := rule.(domain.Node); ok {
+			nodes[n] = node
+
+			if len(node.Produces()) != 0 {
+				producers = append(producers, n)
+			}
+		}
+	}
+
+	parents := make([][]int, len(rules))
+
+	for to, consumer := range nodes {
+		if consumer == nil || len(consumer.Produces()) == 0 {
+			for _, from := range producers {
+				if from != to {
+					parents[to] = append(parents[to], from)
+				}
+			}
+
+			continue
+		}
+
+		for _, from := range producers {
+			if from != to && feeds(nodes[from], consumer) {
+				parents[to] = append(parents[to], from)
+			}
+		}
+	}
+
+	return parents
+}
+
+// feeds проверяет, обновляет ли правило producer таблицы, используемые правилом consumer.
+func feeds(producer, consumer domain.Node) bool {
+	for _, consumed := range domain.Consumes(consumer) {
+		for _, produced := range producer.Produces() {
+			if produced.Overlaps(consumed) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,116 @@
+package bus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"github.com/stretchr/testify/assert"
+)
+
+// slowNode обновляет таблицу только после разрешения теста.
+type slowNode struct {
+	fakeNode
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s slowNode) Activate(in <-chan domain.Event, out chan<- domain.Event) {
+	for event := range in {
+		close(s.started)
+		<-s.release
+
+		out <- domain.CausedBy(event, domain.NewUpdateCompleted(s.produces[0], event.Date()))
+	}
+}
+
+func TestDrainFinishesInFlightChain(t *testing.T) {
+	start := domain.NewID("start", "start")
+	middle := domain.NewID("middle", "middle")
+	date := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	step := slowNode{
+		fakeNode: newFakeNode("Step", []domain.ID{start}, middle),
+		started:  make(chan struct{}),
+		release:  make(chan struct{}),
+	}
+	sink := newFakeNode("Sink", []domain.ID{middle})
+	sink.got = make(chan domain.Event, 10)
+
+	bus := EventBus{
+		logger:       lgr.NoOp(),
+		rules:        []domain.Rule{sink, step},
+		drainTimeout: time.Minute,
+		inbox:        make(chan domain.Event),
+		broadcast:    make(chan *envelope),
+		routes:       make(map[domain.Subscription][]chan *envelope),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() {
+		done <- bus.Run(ctx)
+	}()
+
+	assert.Nil(t, bus.Publish(ctx, domain.NewUpdateCompleted(start, date)))
+
+	<-step.started
+	cancel()
+	close(step.release)
+
+	assert.Nil(t, <-done, "Необработанные события при остановке")
+
+	var got []domain.ID
+	for event := range sink.got {
+		got = append(got, event.ID())
+	}
+
+	assert.Equal(t, []domain.ID{middle}, got, "Не завершена цепочка обновлений")
+}
+
+func TestDrainReturnsAfterDeadline(t *testing.T) {
+	start := domain.NewID("start", "start")
+	middle := domain.NewID("middle", "middle")
+	date := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	stuck := slowNode{
+		fakeNode: newFakeNode("Stuck", []domain.ID{start}, middle),
+		started:  make(chan struct{}),
+		release:  make(chan struct{}),
+	}
+	rulesCtx, cancelRules := context.WithCancel(context.Background())
+
+	bus := EventBus{
+		logger:       lgr.NoOp(),
+		rules:        []domain.Rule{stuck},
+		drainTimeout: 10 * time.Millisecond,
+		cancelRules:  cancelRules,
+		inbox:        make(chan domain.Event),
+		broadcast:    make(chan *envelope),
+		routes:       make(map[domain.Subscription][]chan *envelope),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() {
+		done <- bus.Run(ctx)
+	}()
+
+	assert.Nil(t, bus.Publish(ctx, domain.NewUpdateCompleted(start, date)))
+
+	<-stuck.started
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, errRulesRunning, "Не сообщено о незавершенных правилах")
+	case <-time.After(time.Second):
+		t.Fatal("Остановка не завершилась после истечения срока")
+	}
+
+	assert.ErrorIs(t, rulesCtx.Err(), context.Canceled, "Контекст правил не отменен")
+}
@@ -0,0 +1,227 @@
+package bus
+
+import (
+	"errors"
+	"fmt"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"strings"
+)
+
+var (
+	// errRuleWithoutDependencies правило не декларирует используемые и обновляемые таблицы.
+	errRuleWithoutDependencies = errors.New("rule doesn't declare dependencies")
+	// errOrphanConsumer правило зависит от таблицы, которую не обновляет ни одно правило.
+	errOrphanConsumer = errors.New("consumed table has no producer")
+	// errDependencyCycle правила зависят друг от друга по кругу.
+	errDependencyCycle = errors.New("rules dependency cycle")
+)
+
+// Graph - граф зависимостей правил через таблицы, которые они обновляют и используют.
+type Graph struct {
+	Rules []GraphRule `json:"rules"`
+	Edges []GraphEdge `json:"edges"`
+	// tables конкретные таблицы, обновляемые правилами
+	tables []domain.ID
+}
+
+// GraphRule - правило с используемыми и обновляемыми им таблицами.
+type GraphRule struct {
+	Name     string   `json:"name"`
+	Consumes []string `json:"consumes"`
+	Produces []string `json:"produces"`
+}
+
+// GraphEdge - зависимость правила To от таблицы Table, которую обновляет правило From.
+type GraphEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Table string `json:"table"`
+}
+
+// newGraph строит граф зависимостей правил и проверяет его корректность.
+//
+// Каждая используемая таблица должна обновляться хотя бы одним правилом, а зависимости не должны образовывать
+// циклов, иначе обновление таблиц никогда не завершится.
+func newGraph(rules []domain.Rule) (graph Graph, err error) {
+	nodes := make([]domain.Node, 0, len(rules))
+
+	for _, rule := range rules {
+		node, ok := rule.(domain.Node)
+		if !ok {
+			return graph, fmt.Errorf("%w: %T", errRuleWithoutDependencies, rule)
+		}
+
+		nodes = append(nodes, node)
+
+		for _, id := range node.Produces() {
+			if id.Name() != "" {
+				graph.tables = append(graph.tables, id)
+			}
+		}
+
+		graph.Rules = append(graph.Rules, GraphRule{
+			Name:     node.Name(),
+			Consumes: idsToStrings(domain.Consumes(node)),
+			Produces: idsToStrings(node.Produces()),
+		})
+	}
+
+	adjacent := make([][]int, len(nodes))
+
+	for to, consumer := range nodes {
+		for _, consumed := range domain.Consumes(consumer) {
+			found := false
+
+			for from, producer := range nodes {
+				for _, produced := range producer.Produces() {
+					if !produced.Overlaps(consumed) {
+						continue
+					}
+
+					found = true
+					adjacent[from] = append(adjacent[from], to)
+					graph.Edges = append(graph.Edges, GraphEdge{
+						From:  producer.Name(),
+						To:    consumer.Name(),
+						Table: edgeTable(produced, consumed).String(),
+					})
+				}
+			}
+
+			if !found {
+				return graph, fmt.Errorf("%w: %s consumes %s", errOrphanConsumer, consumer.Name(), consumed)
+			}
+		}
+	}
+
+	if cycle := findCycle(adjacent); cycle != nil {
+		names := make([]string, 0, len(cycle))
+		for _, n := range cycle {
+			names = append(names, nodes[n].Name())
+		}
+
+		return graph, fmt.Errorf("%w: %s", errDependencyCycle, strings.Join(names, " -> "))
+	}
+
+	return graph, nil
+}
+
+// edgeTable выбирает более конкретное обозначение таблицы, через которую связаны правила.
+func edgeTable(produced, consumed domain.ID) domain.ID {
+	if consumed.Name() == "" {
+		return produced
+	}
+
+	return consumed
+}
+
+func idsToStrings(ids []domain.ID) []string {
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, id.String())
+	}
+
+	return out
+}
+
+// findCycle ищет цикл поиском в глубину и возвращает его вершины, начиная и заканчивая одной и той же вершиной.
+func findCycle(adjacent [][]int) []int {
+	const (
+		unvisited = iota
+		inStack
+		done
+	)
+
+	state := make([]int, len(adjacent))
+	stack := make([]int, 0, len(adjacent))
+
+	var visit func(n int) []int
+
+	visit = func(n int) []int {
+		state[n] = inStack
+		stack = append(stack, n)
+
+		for _, next := range adjacent[n] {
+			switch state[next] {
+			case inStack:
+				for i, m := range stack {
+					if m == next {
+						return append(append([]int(nil), stack[i:]...), next)
+					}
+				}
+			case unvisited:
+				if cycle := visit(next); cycle != nil {
+					return cycle
+				}
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		state[n] = done
+
+		return nil
+	}
+
+	for n := range adjacent {
+		if state[n] == unvisited {
+			if cycle := visit(n); cycle != nil {
+				return cycle
+			}
+		}
+	}
+
+	return nil
+}
+
+// Producer - название правила, обновляющего таблицу, или пустая строка, если такого правила нет.
+func (g Graph) Producer(id domain.ID) string {
+	group := domain.GroupID(id.Group()).String()
+
+	for _, rule := range g.Rules {
+		for _, produced := range rule.Produces {
+			if produced == id.String() || produced == group {
+				return rule.Name
+			}
+		}
+	}
+
+	return ""
+}
+
+// Declared - проверяет, что таблица явно указана обновляющим ее правилом, а не входит в обновляемую правилом группу.
+func (g Graph) Declared(id domain.ID) bool {
+	for _, rule := range g.Rules {
+		for _, produced := range rule.Produces {
+			if produced == id.String() {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Tables - конкретные таблицы, обновляемые правилами. Таблицы, обновляемые для всей группы, не указываются, так как
+// их названия заранее неизвестны.
+func (g Graph) Tables() []domain.ID {
+	return g.tables
+}
+
+// DOT - представление графа зависимостей в формате Graphviz.
+func (g Graph) DOT() []byte {
+	var dot strings.Builder
+
+	dot.WriteString("digraph rules {\n")
+
+	for _, rule := range g.Rules {
+		fmt.Fprintf(&dot, "\t%q;\n", rule.Name)
+	}
+
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&dot, "\t%q -> %q [label=%q];\n", edge.From, edge.To, edge.Table)
+	}
+
+	dot.WriteString("}\n")
+
+	return []byte(dot.String())
+}
@@ -0,0 +1,123 @@
+package bus
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/end"
+	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type fakeNode struct {
+	fakeRule
+	name     string
+	produces []domain.ID
+}
+
+func (f fakeNode) Name() string {
+	return f.name
+}
+
+func (f fakeNode) Produces() []domain.ID {
+	return f.produces
+}
+
+func newFakeNode(name string, consumes []domain.ID, produces ...domain.ID) fakeNode {
+	subs := make([]domain.Subscription, 0, len(consumes))
+	for _, id := range consumes {
+		subs = append(subs, domain.Subscribe[domain.UpdateCompleted](id))
+	}
+
+	return fakeNode{fakeRule: fakeRule{subs: subs}, name: name, produces: produces}
+}
+
+func TestNewGraph(t *testing.T) {
+	start := domain.NewID("start", "start")
+	quote := domain.NewID("quotes", "AKRN")
+	quotes := domain.GroupID("quotes")
+	result := domain.NewID("result", "result")
+
+	tbl := []struct {
+		rules []domain.Rule
+		edges int
+		err   error
+	}{
+		{
+			[]domain.Rule{
+				newFakeNode("Start", nil, start),
+				newFakeNode("Quotes", []domain.ID{start}, quotes),
+				newFakeNode("Result", []domain.ID{quote}, result),
+			},
+			2,
+			nil,
+		},
+		{
+			[]domain.Rule{
+				newFakeNode("Quotes", []domain.ID{start}, quotes),
+			},
+			0,
+			errOrphanConsumer,
+		},
+		{
+			[]domain.Rule{
+				newFakeNode("Start", []domain.ID{result}, start),
+				newFakeNode("Quotes", []domain.ID{start}, quotes),
+				newFakeNode("Result", []domain.ID{quote}, result),
+			},
+			0,
+			errDependencyCycle,
+		},
+		{
+			[]domain.Rule{fakeRule{}},
+			0,
+			errRuleWithoutDependencies,
+		},
+	}
+
+	for _, testCase := range tbl {
+		graph, err := newGraph(testCase.rules)
+
+		assert.ErrorIs(t, err, testCase.err, "Некорректная ошибка проверки графа")
+
+		if err == nil {
+			assert.Equal(t, testCase.edges, len(graph.Edges), "Некорректное количество зависимостей")
+		}
+	}
+}
+
+func TestNewEventBusGraph(t *testing.T) {
+	client, err := mongo.NewClient()
+	assert.Nil(t, err, "Ошибка создания клиента MongoDB")
+
+	bus, err := NewEventBus(
+		lgr.NoOp(),
+		client.Database("test"),
+		http.DefaultClient,
+		nil,
+		Config{
+			Timeout:          time.Second,
+			DrainTimeout:     time.Minute,
+			EventsTTL:        time.Hour,
+			USDTolerance:     0.02,
+			LiquidityWindows: []int{21},
+			QualitySigma:     6,
+			IndexList:        []string{"IMOEX"},
+			Schedule:         end.Settings{TZ: "Europe/Moscow", ISS: "00:45", CPIDay: 15, CPI: "19:00"},
+		},
+	)
+
+	assert.Nil(t, err, "Некорректный граф зависимостей правил")
+	assert.Contains(t, string(bus.Graph().DOT()), `"IndexListRule" -> "IndexesRule" [label="index_list/index_list"];`)
+	assert.NotContains(t, string(bus.Graph().DOT()), `"DatesRule" -> "IndexesRule"`)
+	assert.Contains(t, string(bus.Graph().DOT()), `"DayEndedRule" -> "CPIRule" [label="day_ended/cpi"];`)
+	assert.Equal(t, "QuotesRule", bus.Graph().Producer(domain.NewID("quotes", "AKRN")))
+	assert.Equal(t, "", bus.Graph().Producer(domain.NewID("unknown", "unknown")))
+	assert.Contains(t, bus.Graph().Tables(), domain.NewID("cpi", "cpi"))
+	assert.NotContains(t, bus.Graph().Tables(), domain.GroupID("quotes"))
+	assert.True(t, bus.Graph().Declared(domain.NewID("cpi", "cpi")))
+	assert.False(t, bus.Graph().Declared(domain.NewID("quotes", "AKRN")))
+}
@@ -0,0 +1,249 @@
+package bus
+
+import (
+	"context"
+	"fmt"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// envelope - событие в процессе рассылки правилам.
+type envelope struct {
+	event domain.Event
+	// logID идентификатор события в журнале, пустой если событие не удалось сохранить
+	logID string
+	// pending количество правил, которые еще не обработали событие
+	pending int32
+	// stop очередь правила, которую нужно закрыть вместо рассылки события
+	stop chan *envelope
+	// handled правила, обработавшие повторно рассылаемое событие до остановки сервиса
+	handled map[string]bool
+}
+
+// eventLog - хранилище журнала событий.
+type eventLog interface {
+	EnsureIndexes(ctx context.Context, ttl time.Duration) error
+	Save(ctx context.Context, event domain.Event) (string, error)
+	MarkProcessed(ctx context.Context, id string) error
+	MarkHandled(ctx context.Context, id, rule string) error
+	Unprocessed(ctx context.Context) ([]repo.LoggedEvent, error)
+}
+
+var _ eventLog = (*repo.EventLog)(nil)
+
+// journal сохраняет события в журнал и отмечает их обработанными после обработки всеми правилами-получателями.
+//
+// Ошибки работы с журналом не останавливают шину событий, а только записываются в лог. Нулевой журнал ничего не
+// сохраняет.
+type journal struct {
+	logger  *lgr.Logger
+	log     eventLog
+	timeout time.Duration
+	// ttl срок хранения обработанных событий
+	ttl time.Duration
+}
+
+// prepare создает индексы журнала.
+func (j *journal) prepare() {
+	if j == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), j.timeout)
+	defer cancel()
+
+	if err := j.log.EnsureIndexes(ctx, j.ttl); err != nil {
+		j.logger.Warnf("EventBus: can't prepare event log -> %s", err)
+	}
+}
+
+func (j *journal) save(event domain.Event) *envelope {
+	env := envelope{event: event}
+
+	if j == nil {
+		return &env
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), j.timeout)
+	defer cancel()
+
+	id, err := j.log.Save(ctx, event)
+	if err != nil {
+		j.logger.Warnf("EventBus: can't save event %s -> %s", event, err)
+	}
+
+	env.logID = id
+
+	return &env
+}
+
+// route устанавливает количество получателей и отмечает событие без получателей обработанным.
+func (j *journal) route(env *envelope, consumers int) {
+	atomic.StoreInt32(&env.pending, int32(consumers))
+
+	if consumers == 0 {
+		j.markProcessed(env)
+	}
+}
+
+// handled отмечает окончание обработки события одним из получателей.
+//
+// Пока остальные получатели не обработали событие, в журнале запоминается обработавшее его правило, чтобы после
+// перезапуска не передавать ему событие повторно.
+func (j *journal) handled(env *envelope, rule string) {
+	if atomic.AddInt32(&env.pending, -1) == 0 {
+		j.markProcessed(env)
+
+		return
+	}
+
+	if j == nil || env.logID == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), j.timeout)
+	defer cancel()
+
+	if err := j.log.MarkHandled(ctx, env.logID, rule); err != nil {
+		j.logger.Warnf("EventBus: can't mark event %s handled by %s -> %s", env.event, rule, err)
+	}
+}
+
+func (j *journal) markProcessed(env *envelope) {
+	if j == nil || env.logID == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), j.timeout)
+	defer cancel()
+
+	if err := j.log.MarkProcessed(ctx, env.logID); err != nil {
+		j.logger.Warnf("EventBus: can't mark event %s processed -> %s", env.event, err)
+	}
+}
+
+// unprocessed загружает события, не обработанные правилами до предыдущей остановки сервиса.
+func (j *journal) unprocessed() []*envelope {
+	if j == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), j.timeout)
+	defer cancel()
+
+	logged, err := j.log.Unprocessed(ctx)
+	if err != nil {
+		j.logger.Warnf("EventBus: can't load unprocessed events -> %s", err)
+
+		return nil
+	}
+
+	envs := make([]*envelope, 0, len(logged))
+
+	for _, record := range logged {
+		if record.Err != nil {
+			j.logger.Warnf("EventBus: skipping unreadable event %s -> %s", record.ID, record.Err)
+			j.markProcessed(&envelope{logID: record.ID})
+
+			continue
+		}
+
+		env := envelope{event: record.Event, logID: record.ID, handled: make(map[string]bool, len(record.Handled))}
+		for _, rule := range record.Handled {
+			env.handled[rule] = true
+		}
+
+		envs = append(envs, &env)
+	}
+
+	return envs
+}
+
+// handling отслеживает события, переданные правилу, до окончания их обработки.
+//
+// Правило без подтверждений обрабатывает события последовательно, поэтому предыдущее событие считается обработанным,
+// когда правило принимает следующее, а последнее - когда правило завершает работу.
+type handling struct {
+	journal  *journal
+	rule     string
+	explicit bool
+
+	// last последнее переданное правилу без подтверждений событие
+	last *envelope
+
+	lock sync.Mutex
+	// expected события, подтверждения обработки которых ожидаются от правила, по их идентификаторам
+	expected map[string]*envelope
+}
+
+func newHandling(j *journal, rule domain.Rule) *handling {
+	_, explicit := rule.(domain.Acknowledger)
+
+	return &handling{journal: j, rule: ruleName(rule), explicit: explicit, expected: make(map[string]*envelope)}
+}
+
+// ruleName - название правила для учета обработанных им событий в журнале.
+func ruleName(rule domain.Rule) string {
+	if node, ok := rule.(domain.Node); ok {
+		return node.Name()
+	}
+
+	return fmt.Sprintf("%T", rule)
+}
+
+// expect регистрирует событие, которое может быть передано правилу с подтверждениями.
+//
+// Регистрация происходит до передачи, так как правило может подтвердить обработку раньше, чем завершится отправка.
+func (h *handling) expect(env *envelope) {
+	if !h.explicit {
+		return
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.expected[env.event.Trace().ID] = env
+}
+
+// sent отмечает передачу события правилу.
+func (h *handling) sent(env *envelope) {
+	if h.explicit {
+		return
+	}
+
+	prev := h.last
+	h.last = env
+
+	if prev != nil {
+		h.journal.handled(prev, h.rule)
+	}
+}
+
+// ack - подтверждение обработки события правилом.
+func (h *handling) ack(event domain.Event) {
+	h.lock.Lock()
+	env, ok := h.expected[event.Trace().ID]
+	delete(h.expected, event.Trace().ID)
+	h.lock.Unlock()
+
+	if ok {
+		h.journal.handled(env, h.rule)
+	}
+}
+
+// finished отмечает завершение работы правила.
+//
+// Неподтвержденные события правила с подтверждениями остаются необработанными и будут повторно разосланы после
+// перезапуска.
+func (h *handling) finished() {
+	if h.explicit || h.last == nil {
+		return
+	}
+
+	h.journal.handled(h.last, h.rule)
+	h.last = nil
+}
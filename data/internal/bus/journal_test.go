@@ -0,0 +1,259 @@
+package bus
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeLog хранит события в памяти и имитирует задержку обращений к базе данных.
+type fakeLog struct {
+	latency time.Duration
+
+	lock      sync.Mutex
+	saved     int
+	processed map[string]bool
+	handled   map[string][]string
+	// logged необработанные события, загружаемые при запуске
+	logged []repo.LoggedEvent
+}
+
+func newFakeLog(latency time.Duration) *fakeLog {
+	return &fakeLog{latency: latency, processed: make(map[string]bool), handled: make(map[string][]string)}
+}
+
+func (f *fakeLog) EnsureIndexes(_ context.Context, _ time.Duration) error {
+	return nil
+}
+
+func (f *fakeLog) Save(_ context.Context, _ domain.Event) (string, error) {
+	time.Sleep(f.latency)
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.saved++
+
+	return strconv.Itoa(f.saved), nil
+}
+
+func (f *fakeLog) MarkProcessed(_ context.Context, id string) error {
+	time.Sleep(f.latency)
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.processed[id] = true
+
+	return nil
+}
+
+func (f *fakeLog) MarkHandled(_ context.Context, id, rule string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.handled[id] = append(f.handled[id], rule)
+
+	return nil
+}
+
+func (f *fakeLog) Unprocessed(_ context.Context) ([]repo.LoggedEvent, error) {
+	return f.logged, nil
+}
+
+func (f *fakeLog) isProcessed(id string) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.processed[id]
+}
+
+// holdRule последовательно обрабатывает события, ожидая разрешения теста на окончание обработки каждого.
+type holdRule struct {
+	got     chan domain.Event
+	release chan struct{}
+}
+
+func (h holdRule) Activate(in <-chan domain.Event, _ chan<- domain.Event) {
+	for event := range in {
+		h.got <- event
+		<-h.release
+	}
+}
+
+// received - событие, полученное правилом с подтверждениями, и функция подтверждения.
+type received struct {
+	event domain.Event
+	ack   func(domain.Event)
+}
+
+// ackRule передает тесту полученные события для подтверждения их обработки.
+type ackRule struct {
+	got chan received
+}
+
+func (a ackRule) Activate(_ <-chan domain.Event, _ chan<- domain.Event) {
+	panic("rule with acks activated without acks")
+}
+
+func (a ackRule) ActivateWithAck(in <-chan domain.Event, _ chan<- domain.Event, ack func(domain.Event)) {
+	for event := range in {
+		a.got <- received{event: event, ack: ack}
+	}
+}
+
+func newJournaledBus(log *fakeLog, rules ...domain.Rule) *EventBus {
+	return &EventBus{
+		logger:    lgr.NoOp(),
+		rules:     rules,
+		journal:   &journal{logger: lgr.NoOp(), log: log, timeout: time.Second},
+		inbox:     make(chan domain.Event),
+		broadcast: make(chan *envelope),
+		routes:    make(map[domain.Subscription][]chan *envelope),
+	}
+}
+
+func TestJournalMarksProcessedAfterSequentialHandling(t *testing.T) {
+	log := newFakeLog(0)
+	rule := holdRule{got: make(chan domain.Event), release: make(chan struct{})}
+	bus := newJournaledBus(log, rule)
+	date := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	bus.activateConsumers()
+
+	go bus.broadcastToConsumers()
+
+	bus.broadcast <- bus.journal.save(domain.NewUpdateCompleted(domain.NewID("a", "a"), date))
+	<-rule.got
+
+	assert.False(t, log.isProcessed("1"), "Событие отмечено обработанным при получении правилом")
+
+	bus.broadcast <- bus.journal.save(domain.NewUpdateCompleted(domain.NewID("b", "b"), date))
+	rule.release <- struct{}{}
+	<-rule.got
+
+	assert.Eventually(t, func() bool { return log.isProcessed("1") }, time.Second, time.Millisecond)
+	assert.False(t, log.isProcessed("2"), "Событие отмечено обработанным до окончания обработки")
+
+	close(bus.broadcast)
+	rule.release <- struct{}{}
+	bus.wg.Wait()
+
+	assert.True(t, log.isProcessed("2"), "Последнее событие не отмечено обработанным после остановки правила")
+}
+
+func TestJournalMarksProcessedAfterAck(t *testing.T) {
+	log := newFakeLog(0)
+	rule := ackRule{got: make(chan received, 2)}
+	bus := newJournaledBus(log, rule)
+	date := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	bus.activateConsumers()
+
+	go bus.broadcastToConsumers()
+
+	bus.broadcast <- bus.journal.save(domain.NewUpdateCompleted(domain.NewID("a", "a"), date))
+	bus.broadcast <- bus.journal.save(domain.NewUpdateCompleted(domain.NewID("b", "b"), date))
+
+	<-rule.got
+	second := <-rule.got
+
+	assert.False(t, log.isProcessed("1"), "Событие отмечено обработанным без подтверждения")
+	assert.False(t, log.isProcessed("2"), "Событие отмечено обработанным без подтверждения")
+
+	second.ack(second.event)
+
+	assert.True(t, log.isProcessed("2"), "Подтвержденное событие не отмечено обработанным")
+
+	close(bus.broadcast)
+	bus.wg.Wait()
+
+	assert.False(t, log.isProcessed("1"), "Неподтвержденное событие отмечено обработанным после остановки правила")
+}
+
+func TestJournalRecordsHandlingRules(t *testing.T) {
+	log := newFakeLog(0)
+	subs := []domain.Subscription{domain.SubscribeAll[domain.UpdateCompleted]()}
+	first := fakeNode{fakeRule: fakeRule{subs: subs, got: make(chan domain.Event, 1)}, name: "First"}
+	second := fakeNode{fakeRule: fakeRule{subs: subs, got: make(chan domain.Event, 1)}, name: "Second"}
+	bus := newJournaledBus(log, first, second)
+
+	bus.activateConsumers()
+
+	go bus.broadcastToConsumers()
+
+	bus.broadcast <- bus.journal.save(domain.NewUpdateCompleted(domain.NewID("a", "a"), time.Now()))
+
+	close(bus.broadcast)
+	bus.wg.Wait()
+
+	assert.Len(t, log.handled["1"], 1, "Не запомнено правило, первым обработавшее событие")
+	assert.True(t, log.isProcessed("1"), "Событие не отмечено обработанным всеми правилами")
+}
+
+func TestJournalReplay(t *testing.T) {
+	log := newFakeLog(0)
+	event := domain.NewUpdateCompleted(domain.NewID("a", "a"), time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+	log.logged = []repo.LoggedEvent{
+		{ID: "1", Err: repo.ErrUnknownEvent},
+		{ID: "2", Event: event, Handled: []string{"First"}},
+	}
+
+	subs := []domain.Subscription{domain.SubscribeAll[domain.UpdateCompleted]()}
+	first := fakeNode{fakeRule: fakeRule{subs: subs, got: make(chan domain.Event, 1)}, name: "First"}
+	second := fakeNode{fakeRule: fakeRule{subs: subs, got: make(chan domain.Event, 1)}, name: "Second"}
+	bus := newJournaledBus(log, first, second)
+
+	bus.activateConsumers()
+
+	go bus.broadcastToConsumers()
+
+	bus.replayUnprocessed()
+
+	close(bus.broadcast)
+	bus.wg.Wait()
+
+	assert.True(t, log.isProcessed("1"), "Невосстановимое событие не отмечено обработанным")
+	assert.Len(t, first.got, 0, "Событие повторно передано обработавшему его правилу")
+	assert.Equal(t, domain.Event(event), <-second.got, "Событие не передано необработавшему его правилу")
+	assert.True(t, log.isProcessed("2"), "Повторно разосланное событие не отмечено обработанным")
+}
+
+// BenchmarkJournaledDispatch оценивает пропускную способность рассылки с учетом сохранения каждого события в журнал и
+// отметки о его обработке при задержке обращений к базе данных.
+func BenchmarkJournaledDispatch(b *testing.B) {
+	for _, latency := range []time.Duration{0, 100 * time.Microsecond} {
+		b.Run(latency.String(), func(b *testing.B) {
+			rules := make([]domain.Rule, 0, 3)
+			for n := 0; n < cap(rules); n++ {
+				rules = append(rules, fakeRule{
+					subs: []domain.Subscription{domain.SubscribeAll[domain.UpdateCompleted]()},
+					got:  make(chan domain.Event, b.N),
+				})
+			}
+
+			bus := newJournaledBus(newFakeLog(latency), rules...)
+			date := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+			bus.activateConsumers()
+
+			go bus.broadcastToConsumers()
+
+			b.ResetTimer()
+
+			for n := 0; n < b.N; n++ {
+				bus.broadcast <- bus.journal.save(domain.NewUpdateCompleted(domain.NewID("a", "a"), date))
+			}
+
+			close(bus.broadcast)
+			bus.wg.Wait()
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package bus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var eventsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "data_bus_events_total",
+		Help: "Number of events published to the event bus by type.",
+	},
+	[]string{"type"},
+)
@@ -10,16 +10,20 @@ const _timeFormat = "2006-01-02"
 // Event - событие, произошедшее во время работы программы.
 type Event interface {
 	Versioned
+	Traced
 	fmt.Stringer
 }
 
 // UpdateCompleted - событие удачного обновления таблицы.
 type UpdateCompleted struct {
 	ver
+	meta
 }
 
 func NewUpdateCompleted(id ID, date time.Time) UpdateCompleted {
-	return UpdateCompleted{ver: ver{id: id, date: date}}
+	v := ver{id: id, date: date}
+
+	return UpdateCompleted{ver: v, meta: newMeta(v)}
 }
 
 func (u UpdateCompleted) String() string {
@@ -32,16 +36,23 @@ func (u UpdateCompleted) String() string {
 // ErrorOccurred - событие неудачного обновления таблицы.
 type ErrorOccurred struct {
 	ver
+	meta
 	err error
 }
 
 func NewErrorOccurred(v Versioned, err error) ErrorOccurred {
 	return ErrorOccurred{
-		ver: ver{id: v.ID(), date: v.Date()},
-		err: err,
+		ver:  ver{id: v.ID(), date: v.Date()},
+		meta: newMeta(v),
+		err:  err,
 	}
 }
 
+// Err - ошибка, возникшая при обновлении.
+func (e ErrorOccurred) Err() error {
+	return e.err
+}
+
 func (e ErrorOccurred) String() string {
 	return fmt.Sprintf(
 		"ErrorOccurred(%s, %s)",
@@ -49,3 +60,267 @@ func (e ErrorOccurred) String() string {
 		e.err,
 	)
 }
+
+// RevisionDetected - событие пересмотра исторических данных таблицы, потребовавшего ее полной перезаписи.
+type RevisionDetected struct {
+	ver
+	meta
+	reason error
+}
+
+func NewRevisionDetected(v Versioned, reason error) RevisionDetected {
+	return RevisionDetected{
+		ver:    ver{id: v.ID(), date: v.Date()},
+		meta:   newMeta(v),
+		reason: reason,
+	}
+}
+
+// Reason - описание пересмотра данных.
+func (r RevisionDetected) Reason() error {
+	return r.reason
+}
+
+func (r RevisionDetected) String() string {
+	return fmt.Sprintf(
+		"RevisionDetected(%s, %s)",
+		r.ver,
+		r.reason,
+	)
+}
+
+// ListingChanged - событие изменения параметров листинга бумаги.
+//
+// Вид изменения и старое и новое значение параметра описываются в свободной форме правилом, обнаружившим изменение.
+type ListingChanged struct {
+	ver
+	meta
+	ticker   string
+	kind     string
+	oldValue string
+	newValue string
+}
+
+func NewListingChanged(v Versioned, ticker, kind, oldValue, newValue string) ListingChanged {
+	return ListingChanged{
+		ver:      ver{id: v.ID(), date: v.Date()},
+		meta:     newMeta(v),
+		ticker:   ticker,
+		kind:     kind,
+		oldValue: oldValue,
+		newValue: newValue,
+	}
+}
+
+// Ticker - тикер бумаги до изменения, а для новых бумаг - новый тикер.
+func (l ListingChanged) Ticker() string {
+	return l.ticker
+}
+
+// Kind - вид изменения.
+func (l ListingChanged) Kind() string {
+	return l.kind
+}
+
+// OldValue - значение параметра до изменения.
+func (l ListingChanged) OldValue() string {
+	return l.oldValue
+}
+
+// NewValue - значение параметра после изменения.
+func (l ListingChanged) NewValue() string {
+	return l.newValue
+}
+
+func (l ListingChanged) String() string {
+	return fmt.Sprintf(
+		"ListingChanged(%s, %s, %s, %s -> %s)",
+		l.ver,
+		l.ticker,
+		l.kind,
+		l.oldValue,
+		l.newValue,
+	)
+}
+
+// RetryRequested - запрос на повторное обновление таблицы определенным правилом.
+type RetryRequested struct {
+	ver
+	meta
+	rule string
+}
+
+func NewRetryRequested(id ID, date time.Time, rule string) RetryRequested {
+	v := ver{id: id, date: date}
+
+	return RetryRequested{
+		ver:  v,
+		meta: newMeta(v),
+		rule: rule,
+	}
+}
+
+// Rule - название правила, которое должно обновить таблицу.
+func (r RetryRequested) Rule() string {
+	return r.rule
+}
+
+func (r RetryRequested) String() string {
+	return fmt.Sprintf(
+		"RetryRequested(%s, %s)",
+		r.ver,
+		r.rule,
+	)
+}
+
+// SourceUnavailable - событие недоступности внешнего источника данных, обновления из которого временно пропускаются.
+type SourceUnavailable struct {
+	ver
+	meta
+	err error
+}
+
+func NewSourceUnavailable(v Versioned, source string, err error) SourceUnavailable {
+	return SourceUnavailable{
+		ver:  ver{id: SourceID(source), date: v.Date()},
+		meta: newMeta(v),
+		err:  err,
+	}
+}
+
+// Err - ошибка, после которой источник признан недоступным.
+func (s SourceUnavailable) Err() error {
+	return s.err
+}
+
+func (s SourceUnavailable) String() string {
+	return fmt.Sprintf(
+		"SourceUnavailable(%s, %s)",
+		s.ver,
+		s.err,
+	)
+}
+
+// SourceRecovered - событие восстановления доступности внешнего источника данных.
+type SourceRecovered struct {
+	ver
+	meta
+}
+
+func NewSourceRecovered(v Versioned, source string) SourceRecovered {
+	return SourceRecovered{
+		ver:  ver{id: SourceID(source), date: v.Date()},
+		meta: newMeta(v),
+	}
+}
+
+func (s SourceRecovered) String() string {
+	return fmt.Sprintf(
+		"SourceRecovered(%s)",
+		s.ver,
+	)
+}
+
+// UpdateStarted - событие начала обновления таблицы правилом.
+type UpdateStarted struct {
+	ver
+	meta
+	rule string
+}
+
+func NewUpdateStarted(v Versioned, rule string) UpdateStarted {
+	return UpdateStarted{
+		ver:  ver{id: v.ID(), date: v.Date()},
+		meta: newMeta(v),
+		rule: rule,
+	}
+}
+
+// Rule - название правила, обновляющего таблицу.
+func (u UpdateStarted) Rule() string {
+	return u.rule
+}
+
+func (u UpdateStarted) String() string {
+	return fmt.Sprintf(
+		"UpdateStarted(%s, %s)",
+		u.ver,
+		u.rule,
+	)
+}
+
+// UpdateSkipped - событие завершения обновления таблицы без изменений, так как новых данных нет.
+type UpdateSkipped struct {
+	ver
+	meta
+}
+
+func NewUpdateSkipped(v Versioned) UpdateSkipped {
+	return UpdateSkipped{
+		ver:  ver{id: v.ID(), date: v.Date()},
+		meta: newMeta(v),
+	}
+}
+
+func (u UpdateSkipped) String() string {
+	return fmt.Sprintf(
+		"UpdateSkipped(%s)",
+		u.ver,
+	)
+}
+
+// ValidationFailed - событие неудачного обновления таблицы из-за некорректных новых данных.
+type ValidationFailed struct {
+	ver
+	meta
+	err error
+}
+
+func NewValidationFailed(v Versioned, err error) ValidationFailed {
+	return ValidationFailed{
+		ver:  ver{id: v.ID(), date: v.Date()},
+		meta: newMeta(v),
+		err:  err,
+	}
+}
+
+// Err - ошибка проверки новых данных.
+func (e ValidationFailed) Err() error {
+	return e.err
+}
+
+func (e ValidationFailed) String() string {
+	return fmt.Sprintf(
+		"ValidationFailed(%s, %s)",
+		e.ver,
+		e.err,
+	)
+}
+
+// GatewayFailed - событие неудачного обновления таблицы из-за ошибки загрузки данных из внешнего источника.
+type GatewayFailed struct {
+	ver
+	meta
+	err error
+}
+
+func NewGatewayFailed(v Versioned, err error) GatewayFailed {
+	return GatewayFailed{
+		ver:  ver{id: v.ID(), date: v.Date()},
+		meta: newMeta(v),
+		err:  err,
+	}
+}
+
+// Err - ошибка загрузки данных.
+func (e GatewayFailed) Err() error {
+	return e.err
+}
+
+func (e GatewayFailed) String() string {
+	return fmt.Sprintf(
+		"GatewayFailed(%s, %s)",
+		e.ver,
+		e.err,
+	)
+}
@@ -23,3 +23,10 @@ func (id ID) Group() Group {
 func (id ID) Name() Name {
 	return id.name
 }
+
+const _sourceGroup = "source"
+
+// SourceID - идентификатор внешнего источника данных в событиях о его доступности.
+func SourceID(source string) ID {
+	return NewID(_sourceGroup, source)
+}
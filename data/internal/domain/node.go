@@ -0,0 +1,50 @@
+package domain
+
+// Node - правило, декларирующее свое место в графе зависимостей таблиц.
+//
+// Используемые правилом таблицы определяются его подписками на события об обновлении таблиц, поэтому граф
+// зависимостей всегда соответствует фактической маршрутизации событий.
+type Node interface {
+	Subscriber
+	// Name - название правила.
+	Name() string
+	// Produces - таблицы, которые обновляет правило. Пустое название таблицы означает любую таблицу группы.
+	Produces() []ID
+}
+
+// GroupID - обозначение любой таблицы группы.
+func GroupID(group Group) ID {
+	return ID{group: group}
+}
+
+// Consumes - таблицы, от обновления которых зависит правило. Пустое название таблицы означает любую таблицу группы.
+//
+// Подписки на все события об обновлении таблиц не учитываются, так как не описывают конкретные зависимости.
+func Consumes(subscriber Subscriber) (ids []ID) {
+	kind := kindOf[UpdateCompleted]()
+
+	for _, sub := range subscriber.Subscriptions() {
+		if sub.kind == kind && sub.group != "" {
+			ids = append(ids, ID{group: sub.group, name: sub.name})
+		}
+	}
+
+	return ids
+}
+
+// Overlaps - проверяет, может ли обновление одной таблицы затрагивать другую с учетом обозначений групп.
+func (id ID) Overlaps(other ID) bool {
+	if id.group != other.group {
+		return false
+	}
+
+	return id.name == "" || other.name == "" || id.name == other.name
+}
+
+func (id ID) String() string {
+	if id.name == "" {
+		return string(id.group) + "/*"
+	}
+
+	return string(id.group) + "/" + string(id.name)
+}
@@ -10,3 +10,11 @@ package domain
 type Rule interface {
 	Activate(in <-chan Event, out chan<- Event)
 }
+
+// Acknowledger - правило, которое обрабатывает события асинхронно или накапливает их для совместной обработки.
+//
+// Такое правило само сообщает об окончании обработки каждого полученного события, вызывая ack. События, переданные
+// остальным правилам, считаются обработанными, когда правило принимает следующее событие или завершает работу.
+type Acknowledger interface {
+	ActivateWithAck(in <-chan Event, out chan<- Event, ack func(Event))
+}
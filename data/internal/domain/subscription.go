@@ -0,0 +1,52 @@
+package domain
+
+import "reflect"
+
+// Subscription описывает события определенного типа, которые нужны правилу.
+//
+// Пустые группа или название таблицы означают любые значения.
+type Subscription struct {
+	kind  reflect.Type
+	group Group
+	name  Name
+}
+
+// Subscribe создает подписку на события заданного типа, относящиеся к таблице.
+func Subscribe[E Event](id ID) Subscription {
+	return Subscription{kind: kindOf[E](), group: id.group, name: id.name}
+}
+
+// SubscribeGroup создает подписку на события заданного типа, относящиеся к любой таблице группы.
+func SubscribeGroup[E Event](group Group) Subscription {
+	return Subscription{kind: kindOf[E](), group: group}
+}
+
+// SubscribeAll создает подписку на все события заданного типа.
+func SubscribeAll[E Event]() Subscription {
+	return Subscription{kind: kindOf[E]()}
+}
+
+// Keys - ключи, по которым событие может быть найдено среди подписок.
+//
+// Ключи соответствуют подпискам на таблицу события, его группу и на все события данного типа.
+func Keys(event Event) [3]Subscription {
+	kind := reflect.TypeOf(event)
+	id := event.ID()
+
+	return [3]Subscription{
+		{kind: kind, group: id.group, name: id.name},
+		{kind: kind, group: id.group},
+		{kind: kind},
+	}
+}
+
+func kindOf[E Event]() reflect.Type {
+	return reflect.TypeOf((*E)(nil)).Elem()
+}
+
+// Subscriber - правило, которое получает только события из своих подписок.
+//
+// Правила, не реализующие данный интерфейс, получают все события.
+type Subscriber interface {
+	Subscriptions() []Subscription
+}
@@ -2,10 +2,25 @@ package domain
 
 import "time"
 
+// TableMeta - служебные сведения о сохраненной таблице, позволяющие определить изменение данных без их загрузки.
+type TableMeta struct {
+	// Updated - время последнего сохранения таблицы.
+	Updated time.Time
+	// Source - внешний источник данных или правило, обновившее таблицу.
+	Source string
+	// Schema - версия формата хранения таблицы.
+	Schema int
+	// Rows - количество строк.
+	Rows int
+	// Hash - хэш содержимого, изменяющийся при каждом изменении строк.
+	Hash string
+}
+
 // Table представляет таблицу с данными, актуальными на конкретную дату.
 type Table[R any] struct {
 	ver
 	rows []R
+	meta TableMeta
 }
 
 func NewEmptyTable[R any](id ID) Table[R] {
@@ -21,6 +36,27 @@ func NewTable[R any](id ID, date time.Time, rows []R) Table[R] {
 	}
 }
 
+// RestoreTable восстанавливает сохраненную таблицу вместе со служебными сведениями о ней.
+func RestoreTable[R any](id ID, date time.Time, rows []R, meta TableMeta) Table[R] {
+	return Table[R]{
+		ver:  ver{id: id, date: date},
+		rows: rows,
+		meta: meta,
+	}
+}
+
+// WithSource - таблица с указанием источника данных для сохранения.
+func (t Table[R]) WithSource(source string) Table[R] {
+	t.meta.Source = source
+
+	return t
+}
+
+// Meta - служебные сведения о таблице.
+func (t Table[R]) Meta() TableMeta {
+	return t.meta
+}
+
 func (t Table[R]) Rows() []R {
 	return t.rows
 }
@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+const _eventIDBytes = 12
+
+// Trace - идентификаторы, связывающие события в причинно-следственные цепочки.
+//
+// Событие, возникшее не в результате другого события, начинает новую цепочку, и его идентификатор совпадает с
+// идентификатором цепочки. Производные события наследуют идентификатор цепочки и ссылаются на непосредственную
+// причину.
+type Trace struct {
+	// ID - уникальный идентификатор события.
+	ID string
+	// Correlation - идентификатор цепочки событий.
+	Correlation string
+	// Causation - идентификатор события, непосредственно вызвавшего данное, или пустая строка для начала цепочки.
+	Causation string
+}
+
+func (t Trace) String() string {
+	return fmt.Sprintf("(id: %s, causation: %s, correlation: %s)", t.ID, t.Causation, t.Correlation)
+}
+
+// Traced - событие, входящее в причинно-следственную цепочку.
+type Traced interface {
+	Trace() Trace
+}
+
+type meta struct {
+	trace Trace
+}
+
+// newMeta создает идентификаторы события, которое является следствием v, если v входит в цепочку событий.
+func newMeta(v Versioned) meta {
+	id := newEventID()
+
+	cause, ok := v.(Traced)
+	if !ok {
+		return meta{trace: Trace{ID: id, Correlation: id}}
+	}
+
+	return meta{trace: Trace{ID: id, Correlation: cause.Trace().Correlation, Causation: cause.Trace().ID}}
+}
+
+// Trace - идентификаторы события в причинно-следственной цепочке.
+func (m meta) Trace() Trace {
+	return m.trace
+}
+
+func (m *meta) setTrace(trace Trace) {
+	m.trace = trace
+}
+
+func newEventID() string {
+	buf := make([]byte, _eventIDBytes)
+	if _, err := rand.Read(buf); err != nil {
+		panic(fmt.Sprintf("can't generate event id -> %s", err))
+	}
+
+	return hex.EncodeToString(buf)
+}
+
+type tracer[E any] interface {
+	*E
+	setTrace(trace Trace)
+}
+
+// CausedBy включает событие в цепочку событий cause в качестве его непосредственного следствия.
+func CausedBy[E Event, P tracer[E]](cause Event, event E) E {
+	P(&event).setTrace(Trace{
+		ID:          event.Trace().ID,
+		Correlation: cause.Trace().Correlation,
+		Causation:   cause.Trace().ID,
+	})
+
+	return event
+}
+
+// WithTrace восстанавливает идентификаторы события, например, при загрузке из журнала.
+func WithTrace[E Event, P tracer[E]](event E, trace Trace) E {
+	P(&event).setTrace(trace)
+
+	return event
+}
@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrace(t *testing.T) {
+	id := NewID("test", "test")
+	date := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	root := NewUpdateCompleted(id, date)
+	assert.Equal(t, root.Trace().ID, root.Trace().Correlation, "Начало цепочки не совпадает с событием")
+	assert.Empty(t, root.Trace().Causation, "У начала цепочки есть причина")
+
+	update := CausedBy(root, NewUpdateCompleted(id, date))
+	assert.NotEqual(t, root.Trace().ID, update.Trace().ID, "Не уникальный идентификатор события")
+	assert.Equal(t, root.Trace().Correlation, update.Trace().Correlation, "Событие не входит в цепочку")
+	assert.Equal(t, root.Trace().ID, update.Trace().Causation, "Некорректная причина события")
+
+	err := NewErrorOccurred(update, errors.New("test"))
+	assert.Equal(t, root.Trace().Correlation, err.Trace().Correlation, "Ошибка не входит в цепочку")
+	assert.Equal(t, update.Trace().ID, err.Trace().Causation, "Некорректная причина ошибки")
+
+	restored := WithTrace(NewErrorOccurred(ver{id: id, date: date}, errors.New("test")), err.Trace())
+	assert.Equal(t, err.Trace(), restored.Trace(), "Не восстановлены идентификаторы события")
+}
@@ -39,3 +39,8 @@ func (v ver) String() string {
 		v.Date().UTC().Format(_timeFormat),
 	)
 }
+
+// Day - дата без времени в UTC, например, для сопоставления начала дневной свечи с датами других таблиц.
+func Day(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+}
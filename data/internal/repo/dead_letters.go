@@ -0,0 +1,154 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+const _deadLettersCollection = "dead_letters"
+
+// ErrDeadLetterNotFound - отсутствует неудачное обновление для заданной таблицы.
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// DeadLetter - неудачное обновление таблицы: с постоянной ошибкой, исчерпанными повторными попытками или пропущенное.
+type DeadLetter struct {
+	Group    string    `bson:"group" json:"group"`
+	Name     string    `bson:"name" json:"name"`
+	Date     time.Time `bson:"date" json:"date"`
+	Rule     string    `bson:"rule" json:"rule"`
+	Error    string    `bson:"error" json:"error"`
+	Attempts int       `bson:"attempts" json:"attempts"`
+	// Retries - количество повторных попыток, для постоянных ошибок повторные попытки не выполняются.
+	Retries int       `bson:"retries" json:"retries"`
+	Created time.Time `bson:"created" json:"created"`
+	// Event - идентификатор неудачного обновления в цепочке событий.
+	Event string `bson:"event" json:"event"`
+	// Correlation - идентификатор цепочки событий, в которой обновление не удалось.
+	Correlation string `bson:"correlation" json:"correlation"`
+	// Source - источник данных правила.
+	Source string `bson:"source" json:"source"`
+	// Skipped - обновление пропущено из-за недоступности источника данных и повторяется после его восстановления.
+	Skipped bool `bson:"skipped" json:"skipped"`
+}
+
+// ID - таблица, обновление которой не удалось.
+func (d DeadLetter) ID() domain.ID {
+	return domain.NewID(d.Group, d.Name)
+}
+
+// Update - неудачное обновление, восстановленное вместе с его местом в цепочке событий.
+//
+// Неудачные обновления, сохраненные без идентификаторов, восстанавливаются как начало новой цепочки.
+func (d DeadLetter) Update() domain.UpdateCompleted {
+	update := domain.NewUpdateCompleted(d.ID(), d.Date)
+	if d.Correlation == "" {
+		return update
+	}
+
+	return domain.WithTrace(update, domain.Trace{ID: d.Event, Correlation: d.Correlation})
+}
+
+// DeadLetterWriter сохраняет неудачные обновления и удаляет их после успешного обновления таблицы.
+type DeadLetterWriter interface {
+	// Save сохраняет неудачное обновление таблицы, заменяя предыдущее.
+	Save(ctx context.Context, letter DeadLetter) error
+	// Delete удаляет неудачное обновление таблицы, если оно было.
+	Delete(ctx context.Context, id domain.ID) error
+}
+
+// DeadLetters обеспечивает хранение неудачных обновлений таблиц - по одному на таблицу.
+type DeadLetters struct {
+	db *mongo.Database
+}
+
+// NewDeadLetters - создает хранилище неудачных обновлений на основе MongoDB.
+func NewDeadLetters(db *mongo.Database) *DeadLetters {
+	return &DeadLetters{db: db}
+}
+
+// EnsureIndexes создает индекс для загрузки неудачных обновлений цепочки событий.
+func (r *DeadLetters) EnsureIndexes(ctx context.Context) error {
+	model := mongo.IndexModel{Keys: bson.D{{Key: "correlation", Value: 1}}}
+
+	if _, err := r.db.Collection(_deadLettersCollection).Indexes().CreateOne(ctx, model); err != nil {
+		return fmt.Errorf("%w: can't create dead letters indexes -> %s", ErrInternal, err)
+	}
+
+	return nil
+}
+
+func deadLetterKey(id domain.ID) string {
+	return fmt.Sprintf("%s/%s", id.Group(), id.Name())
+}
+
+// Save сохраняет неудачное обновление таблицы, заменяя предыдущее.
+func (r *DeadLetters) Save(ctx context.Context, letter DeadLetter) error {
+	collection := r.db.Collection(_deadLettersCollection)
+
+	filter := bson.M{"_id": deadLetterKey(letter.ID())}
+
+	if _, err := collection.ReplaceOne(ctx, filter, letter, options.Replace().SetUpsert(true)); err != nil {
+		return fmt.Errorf("%w: %#v -> %s", ErrTableUpdate, letter.ID(), err)
+	}
+
+	return nil
+}
+
+// Delete удаляет неудачное обновление таблицы, если оно было.
+func (r *DeadLetters) Delete(ctx context.Context, id domain.ID) error {
+	collection := r.db.Collection(_deadLettersCollection)
+
+	if _, err := collection.DeleteOne(ctx, bson.M{"_id": deadLetterKey(id)}); err != nil {
+		return fmt.Errorf("%w: %#v -> %s", ErrTableUpdate, id, err)
+	}
+
+	return nil
+}
+
+// Get загружает неудачное обновление таблицы.
+func (r *DeadLetters) Get(ctx context.Context, id domain.ID) (letter DeadLetter, err error) {
+	collection := r.db.Collection(_deadLettersCollection)
+
+	err = collection.FindOne(ctx, bson.M{"_id": deadLetterKey(id)}).Decode(&letter)
+
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return letter, fmt.Errorf("%w: %#v", ErrDeadLetterNotFound, id)
+	case err != nil:
+		return letter, fmt.Errorf("%w: %#v -> %s", ErrInternal, id, err)
+	}
+
+	return letter, nil
+}
+
+// List загружает все неудачные обновления в порядке их возникновения.
+func (r *DeadLetters) List(ctx context.Context) ([]DeadLetter, error) {
+	return r.find(ctx, bson.M{})
+}
+
+// Chain загружает неудачные обновления цепочки событий в порядке их возникновения.
+func (r *DeadLetters) Chain(ctx context.Context, correlation string) ([]DeadLetter, error) {
+	return r.find(ctx, bson.M{"correlation": correlation})
+}
+
+func (r *DeadLetters) find(ctx context.Context, filter bson.M) ([]DeadLetter, error) {
+	collection := r.db.Collection(_deadLettersCollection)
+
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(bson.M{"created": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("%w: can't load dead letters -> %s", ErrInternal, err)
+	}
+
+	letters := make([]DeadLetter, 0)
+	if err = cursor.All(ctx, &letters); err != nil {
+		return nil, fmt.Errorf("%w: can't decode dead letters -> %s", ErrInternal, err)
+	}
+
+	return letters, nil
+}
@@ -0,0 +1,33 @@
+package repo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeadLetterUpdateJoinsChain(t *testing.T) {
+	id := domain.NewID("quotes", "AKRN")
+	date := time.Date(2021, time.December, 30, 0, 0, 0, 0, time.UTC)
+	failed := domain.CausedBy(domain.NewUpdateCompleted(domain.NewID("day_ended", "day_ended"), date), domain.NewUpdateCompleted(id, date))
+
+	letter := DeadLetter{
+		Group:       string(id.Group()),
+		Name:        string(id.Name()),
+		Date:        date,
+		Event:       failed.Trace().ID,
+		Correlation: failed.Trace().Correlation,
+	}
+
+	retry := domain.CausedBy(letter.Update(), domain.NewRetryRequested(id, date, "QuotesRule"))
+
+	assert.Equal(t, failed.Trace().Correlation, retry.Trace().Correlation, "Запрос вне цепочки неудачного обновления")
+	assert.Equal(t, failed.Trace().ID, retry.Trace().Causation, "Запрос вызван не неудачным обновлением")
+
+	legacy := DeadLetter{Group: string(id.Group()), Name: string(id.Name()), Date: date}
+	retry = domain.CausedBy(legacy.Update(), domain.NewRetryRequested(id, date, "QuotesRule"))
+
+	assert.NotEmpty(t, retry.Trace().Correlation, "Запрос без цепочки событий")
+}
@@ -0,0 +1,339 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+const _eventsCollection = "event_log"
+
+// ErrUnknownEvent - событие не может быть сохранено или восстановлено из журнала.
+var ErrUnknownEvent = errors.New("unknown event type")
+
+const (
+	_updateCompleted   = "UpdateCompleted"
+	_errorOccurred     = "ErrorOccurred"
+	_revisionDetected  = "RevisionDetected"
+	_listingChanged    = "ListingChanged"
+	_retryRequested    = "RetryRequested"
+	_sourceUnavailable = "SourceUnavailable"
+	_sourceRecovered   = "SourceRecovered"
+	_updateStarted     = "UpdateStarted"
+	_updateSkipped     = "UpdateSkipped"
+	_validationFailed  = "ValidationFailed"
+	_gatewayFailed     = "GatewayFailed"
+)
+
+type eventDAO struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	EventID     string             `bson:"event_id"`
+	Correlation string             `bson:"correlation"`
+	Causation   string             `bson:"causation,omitempty"`
+	Type        string             `bson:"type"`
+	Group       string             `bson:"group"`
+	Name        string             `bson:"name"`
+	Date        time.Time          `bson:"date"`
+	Message     string             `bson:"message,omitempty"`
+	Ticker      string             `bson:"ticker,omitempty"`
+	Kind        string             `bson:"kind,omitempty"`
+	OldValue    string             `bson:"old_value,omitempty"`
+	NewValue    string             `bson:"new_value,omitempty"`
+	Rule        string             `bson:"rule,omitempty"`
+	Created     time.Time          `bson:"created"`
+	Processed   bool               `bson:"processed"`
+	Handled     []string           `bson:"handled,omitempty"`
+}
+
+// LoggedEvent - необработанное событие журнала.
+type LoggedEvent struct {
+	// ID идентификатор события в журнале
+	ID    string
+	Event domain.Event
+	// Handled правила, которые уже обработали событие до остановки сервиса
+	Handled []string
+	// Err ошибка восстановления события, которое невозможно повторно обработать
+	Err error
+}
+
+// EventLog - журнал событий, позволяющий повторно обработать события, не обработанные до остановки сервиса.
+type EventLog struct {
+	db *mongo.Database
+}
+
+// NewEventLog - создает журнал событий на основе MongoDB.
+func NewEventLog(db *mongo.Database) *EventLog {
+	return &EventLog{db: db}
+}
+
+// EnsureIndexes создает индексы для загрузки необработанных событий, цепочек и событий таблиц.
+//
+// Обработанные события удаляются по истечении ttl с момента сохранения, а необработанные хранятся до их обработки.
+func (r *EventLog) EnsureIndexes(ctx context.Context, ttl time.Duration) error {
+	models := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "processed", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "correlation", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "group", Value: 1}, {Key: "name", Value: 1}, {Key: "_id", Value: -1}}},
+		{
+			Keys: bson.D{{Key: "created", Value: 1}},
+			Options: options.Index().
+				SetExpireAfterSeconds(int32(ttl.Seconds())).
+				SetPartialFilterExpression(bson.M{"processed": true}),
+		},
+	}
+
+	if _, err := r.db.Collection(_eventsCollection).Indexes().CreateMany(ctx, models); err != nil {
+		return fmt.Errorf("%w: can't create event log indexes -> %s", ErrInternal, err)
+	}
+
+	return nil
+}
+
+// Save сохраняет новое необработанное событие и возвращает его идентификатор в журнале.
+func (r *EventLog) Save(ctx context.Context, event domain.Event) (string, error) {
+	dao, err := toEventDAO(event)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := r.db.Collection(_eventsCollection).InsertOne(ctx, dao)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s -> %s", ErrTableUpdate, event, err)
+	}
+
+	id, _ := res.InsertedID.(primitive.ObjectID)
+
+	return id.Hex(), nil
+}
+
+// MarkProcessed отмечает событие в журнале как обработанное.
+func (r *EventLog) MarkProcessed(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("%w: bad event id %s -> %s", ErrInternal, id, err)
+	}
+
+	filter := bson.M{"_id": oid}
+	update := bson.M{"$set": bson.M{"processed": true}}
+
+	if _, err := r.db.Collection(_eventsCollection).UpdateOne(ctx, filter, update); err != nil {
+		return fmt.Errorf("%w: event %s -> %s", ErrTableUpdate, id, err)
+	}
+
+	return nil
+}
+
+// MarkHandled отмечает, что событие журнала обработано правилом, чтобы не передавать его правилу повторно.
+func (r *EventLog) MarkHandled(ctx context.Context, id, rule string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("%w: bad event id %s -> %s", ErrInternal, id, err)
+	}
+
+	filter := bson.M{"_id": oid}
+	update := bson.M{"$addToSet": bson.M{"handled": rule}}
+
+	if _, err := r.db.Collection(_eventsCollection).UpdateOne(ctx, filter, update); err != nil {
+		return fmt.Errorf("%w: event %s -> %s", ErrTableUpdate, id, err)
+	}
+
+	return nil
+}
+
+// Unprocessed загружает необработанные события в порядке их сохранения.
+//
+// Записи, которые не удалось восстановить, возвращаются с ошибкой, чтобы не мешать повторной обработке остальных.
+func (r *EventLog) Unprocessed(ctx context.Context) ([]LoggedEvent, error) {
+	opts := options.Find().SetSort(bson.M{"_id": 1})
+
+	cursor, err := r.db.Collection(_eventsCollection).Find(ctx, bson.M{"processed": false}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%w: can't load events -> %s", ErrInternal, err)
+	}
+
+	defer func() { _ = cursor.Close(ctx) }()
+
+	var events []LoggedEvent
+
+	for cursor.Next(ctx) {
+		var dao eventDAO
+
+		if err := cursor.Decode(&dao); err != nil {
+			oid, _ := cursor.Current.Lookup("_id").ObjectIDOK()
+			err = fmt.Errorf("%w: can't decode event %s -> %s", ErrUnknownEvent, oid.Hex(), err)
+			events = append(events, LoggedEvent{ID: oid.Hex(), Err: err})
+
+			continue
+		}
+
+		event, err := dao.toEvent()
+		events = append(events, LoggedEvent{ID: dao.ID.Hex(), Event: event, Handled: dao.Handled, Err: err})
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("%w: can't decode events -> %s", ErrInternal, err)
+	}
+
+	return events, nil
+}
+
+// Chain загружает все события цепочки в порядке их сохранения.
+func (r *EventLog) Chain(ctx context.Context, correlation string) ([]domain.Event, error) {
+	opts := options.Find().SetSort(bson.M{"_id": 1})
+
+	cursor, err := r.db.Collection(_eventsCollection).Find(ctx, bson.M{"correlation": correlation}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%w: can't load events chain %s -> %s", ErrInternal, correlation, err)
+	}
+
+	var daos []eventDAO
+	if err = cursor.All(ctx, &daos); err != nil {
+		return nil, fmt.Errorf("%w: can't decode events chain %s -> %s", ErrInternal, correlation, err)
+	}
+
+	events := make([]domain.Event, 0, len(daos))
+
+	for _, dao := range daos {
+		event, err := dao.toEvent()
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// Table загружает последние события, относящиеся к таблице, начиная с самых новых.
+func (r *EventLog) Table(ctx context.Context, id domain.ID, limit int64) ([]domain.Event, error) {
+	opts := options.Find().SetSort(bson.M{"_id": -1}).SetLimit(limit)
+	filter := bson.M{"group": string(id.Group()), "name": string(id.Name())}
+
+	cursor, err := r.db.Collection(_eventsCollection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%w: can't load events for %s -> %s", ErrInternal, id, err)
+	}
+
+	var daos []eventDAO
+	if err = cursor.All(ctx, &daos); err != nil {
+		return nil, fmt.Errorf("%w: can't decode events for %s -> %s", ErrInternal, id, err)
+	}
+
+	events := make([]domain.Event, 0, len(daos))
+
+	for _, dao := range daos {
+		event, err := dao.toEvent()
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+func toEventDAO(event domain.Event) (eventDAO, error) {
+	dao := eventDAO{
+		EventID:     event.Trace().ID,
+		Correlation: event.Trace().Correlation,
+		Causation:   event.Trace().Causation,
+		Group:       string(event.ID().Group()),
+		Name:        string(event.ID().Name()),
+		Date:        event.Date(),
+		Created:     time.Now(),
+	}
+
+	switch typed := event.(type) {
+	case domain.UpdateCompleted:
+		dao.Type = _updateCompleted
+	case domain.ErrorOccurred:
+		dao.Type = _errorOccurred
+		dao.Message = fmt.Sprint(typed.Err())
+	case domain.RevisionDetected:
+		dao.Type = _revisionDetected
+		dao.Message = fmt.Sprint(typed.Reason())
+	case domain.ListingChanged:
+		dao.Type = _listingChanged
+		dao.Ticker = typed.Ticker()
+		dao.Kind = typed.Kind()
+		dao.OldValue = typed.OldValue()
+		dao.NewValue = typed.NewValue()
+	case domain.RetryRequested:
+		dao.Type = _retryRequested
+		dao.Rule = typed.Rule()
+	case domain.SourceUnavailable:
+		dao.Type = _sourceUnavailable
+		dao.Message = fmt.Sprint(typed.Err())
+	case domain.SourceRecovered:
+		dao.Type = _sourceRecovered
+	case domain.UpdateStarted:
+		dao.Type = _updateStarted
+		dao.Rule = typed.Rule()
+	case domain.UpdateSkipped:
+		dao.Type = _updateSkipped
+	case domain.ValidationFailed:
+		dao.Type = _validationFailed
+		dao.Message = fmt.Sprint(typed.Err())
+	case domain.GatewayFailed:
+		dao.Type = _gatewayFailed
+		dao.Message = fmt.Sprint(typed.Err())
+	default:
+		return dao, fmt.Errorf("%w: %s", ErrUnknownEvent, event)
+	}
+
+	return dao, nil
+}
+
+// toEvent восстанавливает событие вместе с его местом в цепочке событий.
+//
+// Ошибки восстанавливаются только в виде текстового описания.
+func (dao eventDAO) toEvent() (domain.Event, error) {
+	id := domain.NewID(dao.Group, dao.Name)
+	update := domain.NewUpdateCompleted(id, dao.Date)
+
+	// События, сохраненные до появления цепочек, начинают новую цепочку
+	trace := update.Trace()
+	if dao.EventID != "" {
+		trace = domain.Trace{ID: dao.EventID, Correlation: dao.Correlation, Causation: dao.Causation}
+		update = domain.WithTrace(update, trace)
+	}
+
+	switch dao.Type {
+	case _updateCompleted:
+		return update, nil
+	case _errorOccurred:
+		return domain.WithTrace(domain.NewErrorOccurred(update, errors.New(dao.Message)), trace), nil
+	case _revisionDetected:
+		return domain.WithTrace(domain.NewRevisionDetected(update, errors.New(dao.Message)), trace), nil
+	case _listingChanged:
+		event := domain.NewListingChanged(update, dao.Ticker, dao.Kind, dao.OldValue, dao.NewValue)
+
+		return domain.WithTrace(event, trace), nil
+	case _retryRequested:
+		return domain.WithTrace(domain.NewRetryRequested(id, dao.Date, dao.Rule), trace), nil
+	case _sourceUnavailable:
+		event := domain.NewSourceUnavailable(update, dao.Name, errors.New(dao.Message))
+
+		return domain.WithTrace(event, trace), nil
+	case _sourceRecovered:
+		return domain.WithTrace(domain.NewSourceRecovered(update, dao.Name), trace), nil
+	case _updateStarted:
+		return domain.WithTrace(domain.NewUpdateStarted(update, dao.Rule), trace), nil
+	case _updateSkipped:
+		return domain.WithTrace(domain.NewUpdateSkipped(update), trace), nil
+	case _validationFailed:
+		return domain.WithTrace(domain.NewValidationFailed(update, errors.New(dao.Message)), trace), nil
+	case _gatewayFailed:
+		return domain.WithTrace(domain.NewGatewayFailed(update, errors.New(dao.Message)), trace), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEvent, dao.Type)
+	}
+}
@@ -0,0 +1,89 @@
+package repo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"time"
+)
+
+// _tableSchema - версия формата хранения таблиц, которая увеличивается при его изменении.
+//
+// Во второй версии хэш содержимого стал зависеть только от строк таблицы, а не от способа их сохранения.
+const _tableSchema = 2
+
+type metaDAO struct {
+	Updated time.Time `bson:"updated"`
+	Source  string    `bson:"source"`
+	Schema  int       `bson:"schema"`
+	Rows    int       `bson:"rows"`
+	Hash    string    `bson:"hash"`
+}
+
+// newMetaDAO - служебные сведения о таблице после добавления ее строк к таблице со сведениями prev.
+//
+// Хэш содержимого последовательно продолжается каждой строкой, поэтому одинаковые строки имеют одинаковый хэш
+// независимо от того, сохранены они целиком или добавлены частями.
+func newMetaDAO[R any](table domain.Table[R], prev metaDAO) (metaDAO, error) {
+	rows := make([][]byte, 0, len(table.Rows()))
+
+	for _, row := range table.Rows() {
+		raw, err := bson.Marshal(row)
+		if err != nil {
+			return metaDAO{}, fmt.Errorf("%w: can't hash %#v -> %s", ErrTableUpdate, table.ID(), err)
+		}
+
+		rows = append(rows, raw)
+	}
+
+	return metaDAO{
+		Updated: time.Now(),
+		Source:  table.Meta().Source,
+		Schema:  _tableSchema,
+		Rows:    prev.Rows + len(rows),
+		Hash:    chainHash(prev.Hash, rows),
+	}, nil
+}
+
+// restoreMetaDAO вычисляет служебные сведения о таблице, сохраненной в предыдущем формате, по ее строкам в формате
+// BSON, сохраняя время обновления и источник данных, если они были.
+func restoreMetaDAO(rows []bson.Raw, prev metaDAO) metaDAO {
+	raw := make([][]byte, 0, len(rows))
+	for _, row := range rows {
+		raw = append(raw, row)
+	}
+
+	return metaDAO{
+		Updated: prev.Updated,
+		Source:  prev.Source,
+		Schema:  _tableSchema,
+		Rows:    len(raw),
+		Hash:    chainHash("", raw),
+	}
+}
+
+// chainHash продолжает хэш содержимого prev строками в формате BSON.
+func chainHash(prev string, rows [][]byte) string {
+	hash := prev
+
+	for _, row := range rows {
+		next := sha256.New()
+		next.Write([]byte(hash))
+		next.Write(row)
+		hash = hex.EncodeToString(next.Sum(nil))
+	}
+
+	return hash
+}
+
+func (dao metaDAO) toMeta() domain.TableMeta {
+	return domain.TableMeta{
+		Updated: dao.Updated,
+		Source:  dao.Source,
+		Schema:  dao.Schema,
+		Rows:    dao.Rows,
+		Hash:    dao.Hash,
+	}
+}
@@ -0,0 +1,75 @@
+package repo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type testRow struct {
+	Date  time.Time `bson:"date"`
+	Value float64   `bson:"value"`
+}
+
+func testRows() []testRow {
+	rows := make([]testRow, 0, 5)
+
+	for n := 1; n <= 5; n++ {
+		rows = append(rows, testRow{Date: time.Date(2022, 1, n, 0, 0, 0, 0, time.UTC), Value: float64(n)})
+	}
+
+	return rows
+}
+
+func TestNewMetaDAOIndependentOfWritePath(t *testing.T) {
+	id := domain.NewID("test", "table")
+	rows := testRows()
+
+	replaced, err := newMetaDAO(domain.NewTable(id, rows[4].Date, rows), metaDAO{})
+	assert.Nil(t, err, "Ошибка вычисления сведений о таблице")
+
+	head, err := newMetaDAO(domain.NewTable(id, rows[1].Date, rows[:2]), metaDAO{})
+	assert.Nil(t, err, "Ошибка вычисления сведений о таблице")
+
+	appended, err := newMetaDAO(domain.NewTable(id, rows[4].Date, rows[2:]), head)
+	assert.Nil(t, err, "Ошибка вычисления сведений о таблице")
+
+	assert.Equal(t, _tableSchema, appended.Schema, "Некорректная версия формата")
+	assert.Equal(t, len(rows), appended.Rows, "Некорректное количество строк")
+	assert.Equal(t, replaced.Rows, appended.Rows, "Количество строк зависит от способа сохранения")
+	assert.Equal(t, replaced.Hash, appended.Hash, "Хэш зависит от способа сохранения")
+
+	rows[2].Value = 0
+
+	changed, err := newMetaDAO(domain.NewTable(id, rows[4].Date, rows), metaDAO{})
+	assert.Nil(t, err, "Ошибка вычисления сведений о таблице")
+	assert.NotEqual(t, replaced.Hash, changed.Hash, "Хэш не зависит от содержимого")
+}
+
+func TestRestoreMetaDAOMatchesStoredRows(t *testing.T) {
+	id := domain.NewID("test", "table")
+	rows := testRows()
+
+	meta, err := newMetaDAO(domain.NewTable(id, rows[4].Date, rows), metaDAO{})
+	assert.Nil(t, err, "Ошибка вычисления сведений о таблице")
+
+	raw := make([]bson.Raw, 0, len(rows))
+
+	for _, row := range rows {
+		doc, err := bson.Marshal(row)
+		assert.Nil(t, err, "Ошибка сериализации строки")
+
+		raw = append(raw, doc)
+	}
+
+	legacy := metaDAO{Source: "legacy"}
+	restored := restoreMetaDAO(raw, legacy)
+
+	assert.Equal(t, _tableSchema, restored.Schema, "Некорректная версия формата")
+	assert.Equal(t, "legacy", restored.Source, "Не сохранен источник данных")
+	assert.Equal(t, meta.Rows, restored.Rows, "Некорректное количество строк")
+	assert.Equal(t, meta.Hash, restored.Hash, "Хэш сохраненных строк отличается от хэша таблицы")
+}
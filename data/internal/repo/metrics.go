@@ -0,0 +1,14 @@
+package repo
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var repoDuration = promauto.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name: "data_repo_duration_seconds",
+		Help: "Duration of table repository operations.",
+	},
+	[]string{"op", "group"},
+)
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -15,6 +16,7 @@ type tableDAO[R any] struct {
 	Name domain.Name `bson:"_id"`
 	Date time.Time   `bson:"date"`
 	Rows []R         `bson:"rows"`
+	Meta metaDAO     `bson:"meta"`
 }
 
 // Mongo обеспечивает хранение и загрузку таблиц.
@@ -31,6 +33,8 @@ func NewMongo[R any](db *mongo.Database) *Mongo[R] {
 
 // Get загружает таблицу.
 func (r *Mongo[R]) Get(ctx context.Context, id domain.ID) (table domain.Table[R], err error) {
+	defer prometheus.NewTimer(repoDuration.WithLabelValues("get", string(id.Group()))).ObserveDuration()
+
 	var dao tableDAO[R]
 
 	collection := r.db.Collection(string(id.Group()))
@@ -43,7 +47,9 @@ func (r *Mongo[R]) Get(ctx context.Context, id domain.ID) (table domain.Table[R]
 	case err != nil:
 		err = fmt.Errorf("%w: %#v -> %s", ErrInternal, id, err)
 	default:
-		table = domain.NewTable(id, dao.Date, dao.Rows)
+		meta := dao.Meta.toMeta()
+		meta.Rows = len(dao.Rows)
+		table = domain.RestoreTable(id, dao.Date, dao.Rows, meta)
 	}
 
 	return table, err
@@ -51,10 +57,17 @@ func (r *Mongo[R]) Get(ctx context.Context, id domain.ID) (table domain.Table[R]
 
 // Replace перезаписывает таблицу.
 func (r *Mongo[R]) Replace(ctx context.Context, table domain.Table[R]) error {
+	defer prometheus.NewTimer(repoDuration.WithLabelValues("replace", string(table.Group()))).ObserveDuration()
+
 	collection := r.db.Collection(string(table.Group()))
 
+	meta, err := newMetaDAO(table, metaDAO{})
+	if err != nil {
+		return err
+	}
+
 	filter := bson.M{"_id": table.Name()}
-	update := bson.M{"$set": bson.M{"rows": table.Rows(), "date": table.Date()}}
+	update := bson.M{"$set": bson.M{"rows": table.Rows(), "date": table.Date(), "meta": meta}}
 
 	if _, err := collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true)); err != nil {
 		return fmt.Errorf("%w: %#v -> %s", ErrTableUpdate, table.ID(), err)
@@ -64,11 +77,28 @@ func (r *Mongo[R]) Replace(ctx context.Context, table domain.Table[R]) error {
 }
 
 // Append добавляет строки в конец таблицы.
+//
+// Хэш содержимого вычисляется по хэшу сохраненных строк и новым строкам, поэтому загружать всю таблицу не требуется.
 func (r *Mongo[R]) Append(ctx context.Context, table domain.Table[R]) error {
+	defer prometheus.NewTimer(repoDuration.WithLabelValues("append", string(table.Group()))).ObserveDuration()
+
 	collection := r.db.Collection(string(table.Group()))
 
+	prev, err := r.storedMeta(ctx, table.ID())
+	if err != nil {
+		return err
+	}
+
+	meta, err := newMetaDAO(table, prev)
+	if err != nil {
+		return err
+	}
+
 	filter := bson.M{"_id": table.Name()}
-	update := bson.M{"$push": bson.M{"rows": bson.M{"$each": table.Rows()}}, "$set": bson.M{"date": table.Date()}}
+	update := bson.M{
+		"$push": bson.M{"rows": bson.M{"$each": table.Rows()}},
+		"$set":  bson.M{"date": table.Date(), "meta": meta},
+	}
 
 	if _, err := collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true)); err != nil {
 		return fmt.Errorf("%w: %#v -> %s", ErrTableUpdate, table.ID(), err)
@@ -77,6 +107,33 @@ func (r *Mongo[R]) Append(ctx context.Context, table domain.Table[R]) error {
 	return nil
 }
 
+// storedMeta загружает служебные сведения о сохраненной таблице.
+//
+// Для таблиц, сохраненных в предыдущем формате, они вычисляются по сохраненным строкам.
+func (r *Mongo[R]) storedMeta(ctx context.Context, id domain.ID) (metaDAO, error) {
+	var dao tableDAO[bson.Raw]
+
+	collection := r.db.Collection(string(id.Group()))
+	projection := options.FindOne().SetProjection(bson.M{"meta": 1})
+
+	err := collection.FindOne(ctx, bson.M{"_id": string(id.Name())}, projection).Decode(&dao)
+
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return metaDAO{}, nil
+	case err != nil:
+		return metaDAO{}, fmt.Errorf("%w: %#v -> %s", ErrInternal, id, err)
+	case dao.Meta.Schema == _tableSchema:
+		return dao.Meta, nil
+	}
+
+	if err = collection.FindOne(ctx, bson.M{"_id": string(id.Name())}).Decode(&dao); err != nil {
+		return metaDAO{}, fmt.Errorf("%w: %#v -> %s", ErrInternal, id, err)
+	}
+
+	return restoreMetaDAO(dao.Rows, dao.Meta), nil
+}
+
 // MongoJSON обеспечивает хранение и загрузку таблиц.
 type MongoJSON struct {
 	db *mongo.Database
@@ -91,6 +148,8 @@ func NewMongoJSON(db *mongo.Database) *MongoJSON {
 
 // GetJSON загружает ExtendedJSON представление таблицы.
 func (r *MongoJSON) GetJSON(ctx context.Context, id domain.ID) ([]byte, error) {
+	defer prometheus.NewTimer(repoDuration.WithLabelValues("get_json", string(id.Group()))).ObserveDuration()
+
 	collection := r.db.Collection(string(id.Group()))
 
 	projections := options.FindOne().SetProjection(bson.M{"_id": 0, "rows": 1, "date": 1})
@@ -110,3 +169,33 @@ func (r *MongoJSON) GetJSON(ctx context.Context, id domain.ID) ([]byte, error) {
 
 	return json, nil
 }
+
+// GetMeta загружает дату данных и служебные сведения о таблице.
+//
+// Для таблиц, сохраненных в предыдущем формате, количество строк и хэш вычисляются по сохраненным строкам.
+func (r *MongoJSON) GetMeta(ctx context.Context, id domain.ID) (time.Time, domain.TableMeta, error) {
+	defer prometheus.NewTimer(repoDuration.WithLabelValues("get_meta", string(id.Group()))).ObserveDuration()
+
+	var dao tableDAO[bson.Raw]
+
+	collection := r.db.Collection(string(id.Group()))
+
+	projections := options.FindOne().SetProjection(bson.M{"date": 1, "meta": 1})
+
+	err := collection.FindOne(ctx, bson.M{"_id": id.Name()}, projections).Decode(&dao)
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return time.Time{}, domain.TableMeta{}, fmt.Errorf("%w: %#v", ErrTableNotFound, id)
+	case err != nil:
+		return time.Time{}, domain.TableMeta{}, fmt.Errorf("%w: %#v -> %s", ErrInternal, id, err)
+	case dao.Meta.Schema == _tableSchema:
+		return dao.Date, dao.Meta.toMeta(), nil
+	}
+
+	err = collection.FindOne(ctx, bson.M{"_id": id.Name()}).Decode(&dao)
+	if err != nil {
+		return time.Time{}, domain.TableMeta{}, fmt.Errorf("%w: %#v -> %s", ErrInternal, id, err)
+	}
+
+	return dao.Date, restoreMetaDAO(dao.Rows, dao.Meta).toMeta(), nil
+}
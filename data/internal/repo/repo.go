@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"time"
 )
 
 var (
@@ -24,6 +25,12 @@ type JSONViewer interface {
 	GetJSON(ctx context.Context, id domain.ID) ([]byte, error)
 }
 
+// MetaViewer осуществляет загрузку служебных сведений о таблице без ее строк.
+type MetaViewer interface {
+	// GetMeta загружает дату данных и служебные сведения о таблице.
+	GetMeta(ctx context.Context, id domain.ID) (time.Time, domain.TableMeta, error)
+}
+
 // ReadWrite осуществляет загрузку и сохранение таблиц.
 type ReadWrite[R any] interface {
 	Read[R]
@@ -0,0 +1,170 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"sort"
+	"time"
+)
+
+const _tableStatusCollection = "table_status"
+
+// TableStatus - состояние обновления таблицы.
+type TableStatus struct {
+	Group string `bson:"group" json:"group"`
+	Name  string `bson:"name" json:"name"`
+	// Date - дата последних данных таблицы.
+	Date time.Time `bson:"date,omitempty" json:"date"`
+	// Updated - время последнего удачного обновления.
+	Updated time.Time `bson:"updated,omitempty" json:"updated"`
+	// Checked - время последней проверки наличия новых данных, завершившейся без ошибок.
+	Checked time.Time `bson:"checked,omitempty" json:"checked"`
+	// Error - последняя ошибка обновления.
+	Error string `bson:"error,omitempty" json:"error,omitempty"`
+	// Failed - время последней ошибки обновления.
+	Failed time.Time `bson:"failed,omitempty" json:"failed"`
+	// Rows - количество строк в таблице.
+	Rows int `bson:"-" json:"rows"`
+}
+
+// ID - таблица, к которой относится состояние.
+func (s TableStatus) ID() domain.ID {
+	return domain.NewID(s.Group, s.Name)
+}
+
+// TableStatuses обеспечивает хранение состояния обновления таблиц.
+type TableStatuses struct {
+	db *mongo.Database
+}
+
+// NewTableStatuses - создает хранилище состояния обновления таблиц на основе MongoDB.
+func NewTableStatuses(db *mongo.Database) *TableStatuses {
+	return &TableStatuses{db: db}
+}
+
+// Completed отмечает удачное обновление таблицы данными на дату date.
+func (r *TableStatuses) Completed(ctx context.Context, id domain.ID, date, at time.Time) error {
+	return r.set(ctx, id, bson.M{"date": date, "updated": at, "checked": at})
+}
+
+// Checked отмечает проверку наличия новых данных для таблицы, завершившуюся без ошибок.
+func (r *TableStatuses) Checked(ctx context.Context, id domain.ID, at time.Time) error {
+	return r.set(ctx, id, bson.M{"checked": at})
+}
+
+// Failed отмечает ошибку обновления таблицы.
+func (r *TableStatuses) Failed(ctx context.Context, id domain.ID, err error, at time.Time) error {
+	return r.set(ctx, id, bson.M{"error": fmt.Sprint(err), "failed": at})
+}
+
+func tableStatusKey(id domain.ID) string {
+	return fmt.Sprintf("%s/%s", id.Group(), id.Name())
+}
+
+func (r *TableStatuses) set(ctx context.Context, id domain.ID, fields bson.M) error {
+	fields["group"] = string(id.Group())
+	fields["name"] = string(id.Name())
+
+	collection := r.db.Collection(_tableStatusCollection)
+
+	filter := bson.M{"_id": tableStatusKey(id)}
+	update := bson.M{"$set": fields}
+
+	if _, err := collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true)); err != nil {
+		return fmt.Errorf("%w: %#v -> %s", ErrTableUpdate, id, err)
+	}
+
+	return nil
+}
+
+// List загружает состояние обновления всех таблиц с количеством строк в них.
+//
+// Таблицы из seed, для которых состояние еще не сохранялось, добавляются с пустым состоянием, чтобы были видны
+// таблицы, которые ни разу не обновлялись.
+func (r *TableStatuses) List(ctx context.Context, seed []domain.ID) ([]TableStatus, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "group", Value: 1}, {Key: "name", Value: 1}})
+
+	cursor, err := r.db.Collection(_tableStatusCollection).Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%w: can't load table statuses -> %s", ErrInternal, err)
+	}
+
+	statuses := make([]TableStatus, 0)
+	if err = cursor.All(ctx, &statuses); err != nil {
+		return nil, fmt.Errorf("%w: can't decode table statuses -> %s", ErrInternal, err)
+	}
+
+	statuses = seedStatuses(statuses, seed)
+
+	counts := make(map[string]map[string]int)
+
+	for n, status := range statuses {
+		if _, ok := counts[status.Group]; !ok {
+			if counts[status.Group], err = r.countRows(ctx, status.Group); err != nil {
+				return nil, err
+			}
+		}
+
+		statuses[n].Rows = counts[status.Group][status.Name]
+	}
+
+	return statuses, nil
+}
+
+// seedStatuses добавляет пустые состояния для таблиц без сохраненного состояния, сохраняя сортировку по группе и
+// названию.
+func seedStatuses(statuses []TableStatus, seed []domain.ID) []TableStatus {
+	known := make(map[domain.ID]bool, len(statuses))
+	for _, status := range statuses {
+		known[status.ID()] = true
+	}
+
+	for _, id := range seed {
+		if !known[id] {
+			known[id] = true
+			statuses = append(statuses, TableStatus{Group: string(id.Group()), Name: string(id.Name())})
+		}
+	}
+
+	sort.SliceStable(statuses, func(i, j int) bool {
+		if statuses[i].Group != statuses[j].Group {
+			return statuses[i].Group < statuses[j].Group
+		}
+
+		return statuses[i].Name < statuses[j].Name
+	})
+
+	return statuses
+}
+
+// countRows подсчитывает количество строк во всех таблицах группы.
+func (r *TableStatuses) countRows(ctx context.Context, group string) (map[string]int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$project", Value: bson.M{"rows": bson.M{"$size": bson.M{"$ifNull": bson.A{"$rows", bson.A{}}}}}}},
+	}
+
+	cursor, err := r.db.Collection(group).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("%w: can't count rows in %s -> %s", ErrInternal, group, err)
+	}
+
+	var docs []struct {
+		Name string `bson:"_id"`
+		Rows int    `bson:"rows"`
+	}
+
+	if err = cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("%w: can't decode rows count in %s -> %s", ErrInternal, group, err)
+	}
+
+	counts := make(map[string]int, len(docs))
+	for _, doc := range docs {
+		counts[doc.Name] = doc.Rows
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,34 @@
+package repo
+
+import (
+	"testing"
+
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeedStatuses(t *testing.T) {
+	stored := []TableStatus{
+		{Group: "cpi", Name: "cpi", Error: "failed"},
+		{Group: "quotes", Name: "AKRN"},
+	}
+	seed := []domain.ID{
+		domain.NewID("quotes", "AKRN"),
+		domain.NewID("dates", "dates"),
+		domain.NewID("usd", "usd"),
+		domain.NewID("dates", "dates"),
+	}
+
+	statuses := seedStatuses(stored, seed)
+
+	assert.Equal(t, []TableStatus{
+		{Group: "cpi", Name: "cpi", Error: "failed"},
+		{Group: "dates", Name: "dates"},
+		{Group: "quotes", Name: "AKRN"},
+		{Group: "usd", Name: "usd"},
+	}, statuses)
+}
+
+func TestTableStatusKey(t *testing.T) {
+	assert.Equal(t, "quotes/AKRN", tableStatusKey(domain.NewID("quotes", "AKRN")))
+}
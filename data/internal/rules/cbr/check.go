@@ -0,0 +1,118 @@
+package cbr
+
+import (
+	"errors"
+	"fmt"
+	"github.com/WLM1ke/gomoex"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/usd"
+	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"math"
+	"time"
+)
+
+const _logFormat = `2006-01-02`
+
+// ErrRateMismatch - расхождение курса ЦБ РФ и биржевого курса доллара.
+var ErrRateMismatch = errors.New("cbr and usd rate mismatch")
+
+// check - правило сверки курса ЦБ РФ с ценой закрытия биржевого курса доллара за последнюю общую дату.
+type check struct {
+	logger    *lgr.Logger
+	cbr       repo.Read[Rate]
+	usd       repo.Read[gomoex.Candle]
+	tolerance float64
+	ctxFunc   template.EventCtxFunc
+
+	last time.Time
+}
+
+func (c *check) Subscriptions() []domain.Subscription {
+	return []domain.Subscription{
+		domain.Subscribe[domain.UpdateCompleted](ID),
+		domain.Subscribe[domain.UpdateCompleted](usd.ID),
+	}
+}
+
+// Name - название правила.
+func (c *check) Name() string {
+	return "CBRCheckRule"
+}
+
+// Produces - правило не обновляет таблицы.
+func (c *check) Produces() []domain.ID {
+	return nil
+}
+
+func (c *check) Activate(in <-chan domain.Event, out chan<- domain.Event) {
+	c.logger.Infof("CBRCheckRule: started")
+	defer c.logger.Infof("CBRCheckRule: stopped")
+
+	for event := range in {
+		event, ok := event.(domain.UpdateCompleted)
+		if ok && (event.ID() == ID || event.ID() == usd.ID) {
+			if err := c.process(event); err != nil {
+				out <- domain.NewErrorOccurred(event, err)
+			}
+		}
+	}
+}
+
+func (c *check) process(event domain.UpdateCompleted) error {
+	ctx, cancel := c.ctxFunc()
+	defer cancel()
+
+	rates, err := c.cbr.Get(ctx, ID)
+	if err != nil {
+		return err
+	}
+
+	candles, err := c.usd.Get(ctx, usd.ID)
+	if err != nil {
+		return err
+	}
+
+	rate, candle, ok := lastCommon(rates.Rows(), candles.Rows())
+	if !ok || !c.last.Before(rate.Date) {
+		return nil
+	}
+
+	c.last = rate.Date
+
+	if diff := math.Abs(rate.Close/candle.Close - 1); diff > c.tolerance {
+		return fmt.Errorf(
+			"%w: %s cbr %.4f vs usd %.4f",
+			ErrRateMismatch,
+			rate.Date.Format(_logFormat),
+			rate.Close,
+			candle.Close,
+		)
+	}
+
+	c.logger.Infof("CBRCheckRule: %s checked after %s", rate.Date.Format(_logFormat), event)
+
+	return nil
+}
+
+// lastCommon находит последнюю дату, для которой есть и курс ЦБ РФ, и биржевая свеча.
+func lastCommon(rates []Rate, candles []gomoex.Candle) (Rate, gomoex.Candle, bool) {
+	n, m := len(rates)-1, len(candles)-1
+
+	for n >= 0 && m >= 0 {
+		rateDate := rates[n].Date
+		candleDate := domain.Day(candles[m].Begin)
+
+		switch {
+		case rateDate.Equal(candleDate):
+			return rates[n], candles[m], true
+		case rateDate.After(candleDate):
+			n--
+		default:
+			m--
+		}
+	}
+
+	return Rate{}, gomoex.Candle{}, false
+}
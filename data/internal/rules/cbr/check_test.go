@@ -0,0 +1,25 @@
+package cbr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WLM1ke/gomoex"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLastCommon(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2022, 1, d, 0, 0, 0, 0, time.UTC) }
+
+	rates := []Rate{{Date: day(10), Close: 75}, {Date: day(11), Close: 76}, {Date: day(13), Close: 77}}
+	candles := []gomoex.Candle{{Begin: day(10), Close: 74}, {Begin: day(11), Close: 75}, {Begin: day(12), Close: 76}}
+
+	rate, candle, ok := lastCommon(rates, candles)
+
+	assert.True(t, ok, "Не найдена общая дата")
+	assert.Equal(t, day(11), rate.Date, "Некорректная дата курса")
+	assert.Equal(t, day(11), candle.Begin, "Некорректная дата свечи")
+
+	_, _, ok = lastCommon(rates[2:], candles)
+	assert.False(t, ok, "Найдена несуществующая общая дата")
+}
@@ -0,0 +1,2 @@
+// Package cbr содержит правило обновления официального курса доллара ЦБ РФ и его сверки с биржевым курсом.
+package cbr
@@ -0,0 +1,138 @@
+package cbr
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
+	"golang.org/x/text/encoding/charmap"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	_source       = "CBR"
+	_url          = `https://www.cbr.ru/scripts/XML_dynamic.asp?date_req1=%s&date_req2=%s&VAL_NM_RQ=%s`
+	_usdID        = `R01235`
+	_firstDate    = `01/07/1992`
+	_reqFormat    = `02/01/2006`
+	_recordFormat = `02.01.2006`
+)
+
+type valCurs struct {
+	Records []struct {
+		Date    string `xml:"Date,attr"`
+		Nominal string `xml:"Nominal"`
+		Value   string `xml:"Value"`
+	} `xml:"Record"`
+}
+
+type gateway struct {
+	client *http.Client
+}
+
+// Source - источник данных шлюза.
+func (g gateway) Source() string {
+	return _source
+}
+
+func (g gateway) Get(ctx context.Context, table domain.Table[Rate], date time.Time) ([]Rate, error) {
+	start := _firstDate
+	if !table.IsEmpty() {
+		start = table.LastRow().Date.Format(_reqFormat)
+	}
+
+	url := fmt.Sprintf(_url, start, date.Format(_reqFormat), _usdID)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"%w: can't create request -> %s",
+			template.ErrRuleGateway,
+			err,
+		)
+	}
+
+	resp, err := g.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"%w: can't make request -> %s",
+			template.ErrTransient,
+			err,
+		)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, template.StatusError(resp)
+	}
+
+	return parseXML(resp.Body)
+}
+
+func parseXML(reader io.Reader) ([]Rate, error) {
+	decoder := xml.NewDecoder(reader)
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		if !strings.EqualFold(charset, "windows-1251") {
+			return nil, fmt.Errorf("unknown charset %s", charset)
+		}
+
+		return charmap.Windows1251.NewDecoder().Reader(input), nil
+	}
+
+	var curs valCurs
+	if err := decoder.Decode(&curs); err != nil {
+		return nil, fmt.Errorf(
+			"%w: can't decode xml -> %s",
+			template.ErrRuleGateway,
+			err,
+		)
+	}
+
+	rows := make([]Rate, 0, len(curs.Records))
+
+	for _, record := range curs.Records {
+		date, err := time.Parse(_recordFormat, record.Date)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"%w: can't parse date %s -> %s",
+				template.ErrRuleGateway,
+				record.Date,
+				err,
+			)
+		}
+
+		value, err := parseFloat(record.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		nominal, err := parseFloat(record.Nominal)
+		if err != nil {
+			return nil, err
+		}
+
+		rows = append(rows, Rate{Date: date, Close: value / nominal})
+	}
+
+	return rows, nil
+}
+
+func parseFloat(value string) (float64, error) {
+	float, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+	if err != nil {
+		return 0, fmt.Errorf(
+			"%w: can't parse value %s -> %s",
+			template.ErrRuleGateway,
+			value,
+			err,
+		)
+	}
+
+	return float, nil
+}
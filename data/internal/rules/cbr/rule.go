@@ -0,0 +1,49 @@
+package cbr
+
+import (
+	"github.com/WLM1ke/gomoex"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/dates"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
+	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"time"
+)
+
+const _group = "cbr"
+
+var ID = domain.NewID(_group, "usd")
+
+// Rate - официальный курс ЦБ РФ, установленный на дату.
+type Rate struct {
+	Date  time.Time
+	Close float64
+}
+
+func New(logger *lgr.Logger, db *mongo.Database, client *http.Client, opts template.Options) domain.Rule {
+	return template.NewRule[Rate](
+		"CBRRule",
+		logger,
+		repo.NewMongo[Rate](db),
+		template.NewSelectOnTableUpdate(dates.ID, ID),
+		gateway{client: client},
+		validator,
+		true,
+		opts,
+	)
+}
+
+// NewCheck создает правило сверки курса ЦБ РФ с ценой закрытия биржевого курса доллара.
+//
+// Относительное расхождение больше tolerance приводит к событию-ошибке.
+func NewCheck(logger *lgr.Logger, db *mongo.Database, tolerance float64, ctxFunc template.EventCtxFunc) domain.Rule {
+	return &check{
+		logger:    logger,
+		cbr:       repo.NewMongo[Rate](db),
+		usd:       repo.NewMongo[gomoex.Candle](db),
+		tolerance: tolerance,
+		ctxFunc:   ctxFunc,
+	}
+}
@@ -15,6 +15,7 @@ import (
 )
 
 const (
+	_source    = "Rosstat"
 	_pricesURL = `https://rosstat.gov.ru/storage/mediabank/ind_potreb_cen_12.html`
 
 	_sheet = `ИПЦ`
@@ -48,6 +49,11 @@ type gateway struct {
 	client *http.Client
 }
 
+// Source - источник данных шлюза.
+func (g gateway) Source() string {
+	return _source
+}
+
 func (g gateway) Get(ctx context.Context, table domain.Table[CPI], _ time.Time) ([]CPI, error) {
 	xlsx, err := g.getXLSX(ctx)
 	if err != nil {
@@ -101,7 +107,7 @@ func (g gateway) getXLSX(ctx context.Context) (*excelize.File, error) {
 	if err != nil {
 		return nil, fmt.Errorf(
 			"%w: can't make request -> %s",
-			template.ErrRuleGateway,
+			template.ErrTransient,
 			err,
 		)
 	}
@@ -109,11 +115,7 @@ func (g gateway) getXLSX(ctx context.Context) (*excelize.File, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf(
-			"%w: bad respond status %s",
-			template.ErrRuleGateway,
-			resp.Status,
-		)
+		return nil, template.StatusError(resp)
 	}
 
 	return excelize.OpenReader(resp.Body)
@@ -133,7 +135,7 @@ func (g gateway) getURL(ctx context.Context) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf(
 			"%w: can't make request -> %s",
-			template.ErrRuleGateway,
+			template.ErrTransient,
 			err,
 		)
 	}
@@ -141,11 +143,7 @@ func (g gateway) getURL(ctx context.Context) (string, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf(
-			"%w: bad respond status %s",
-			template.ErrRuleGateway,
-			resp.Status,
-		)
+		return "", template.StatusError(resp)
 	}
 
 	decoder := charmap.Windows1252.NewDecoder()
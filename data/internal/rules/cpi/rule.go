@@ -3,7 +3,6 @@ package cpi
 import (
 	"github.com/WLM1ke/poptimizer/data/internal/domain"
 	"github.com/WLM1ke/poptimizer/data/internal/repo"
-	"github.com/WLM1ke/poptimizer/data/internal/rules/dates"
 	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
 	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -20,15 +19,17 @@ type CPI struct {
 	Close float64
 }
 
-func New(logger *lgr.Logger, db *mongo.Database, client *http.Client, timeout time.Duration) domain.Rule {
+func New(logger *lgr.Logger, db *mongo.Database, client *http.Client, opts template.Options) domain.Rule {
+	cpi := repo.NewMongo[CPI](db)
+
 	return template.NewRule[CPI](
 		"CPIRule",
 		logger,
-		repo.NewMongo[CPI](db),
-		template.NewSelectOnTableUpdate(dates.ID, ID),
+		cpi,
+		&selector{repo: cpi},
 		gateway{client: client},
 		validator,
 		false,
-		template.EventCtxFuncWithTimeout(timeout),
+		opts,
 	)
 }
@@ -0,0 +1,81 @@
+package cpi
+
+import (
+	"context"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/end"
+	"sync"
+	"time"
+)
+
+// selector выбирает индекс потребительских цен после его публикации по расписанию.
+//
+// Росстат может задержать публикацию, поэтому, пока в таблице нет данных за ожидаемый месяц, обновление ежедневно
+// повторяется после окончания торгового дня.
+type selector struct {
+	repo repo.Read[CPI]
+
+	lock sync.Mutex
+	// expected последний день месяца, данные за который должны быть опубликованы
+	expected time.Time
+}
+
+func (s *selector) Subscriptions() []domain.Subscription {
+	return []domain.Subscription{
+		domain.Subscribe[domain.UpdateCompleted](end.CPIID),
+		domain.Subscribe[domain.UpdateCompleted](end.ID),
+	}
+}
+
+func (s *selector) Produces() []domain.ID {
+	return []domain.ID{ID}
+}
+
+func (s *selector) Select(ctx context.Context, event domain.Event) ([]domain.ID, error) {
+	if _, ok := event.(domain.UpdateCompleted); !ok {
+		return nil, nil
+	}
+
+	switch event.ID() {
+	case end.CPIID:
+		s.expect(event.Date())
+
+		return []domain.ID{ID}, nil
+	case end.ID:
+		return s.selectIfMissing(ctx)
+	default:
+		return nil, nil
+	}
+}
+
+func (s *selector) expect(date time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.expected.Before(date) {
+		s.expected = date
+	}
+}
+
+// selectIfMissing выбирает таблицу, если в ней нет данных за ожидаемый месяц.
+func (s *selector) selectIfMissing(ctx context.Context) ([]domain.ID, error) {
+	s.lock.Lock()
+	expected := s.expected
+	s.lock.Unlock()
+
+	if expected.IsZero() {
+		return nil, nil
+	}
+
+	table, err := s.repo.Get(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !table.IsEmpty() && !table.LastRow().Date.Before(expected) {
+		return nil, nil
+	}
+
+	return []domain.ID{ID}, nil
+}
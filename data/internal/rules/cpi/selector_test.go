@@ -0,0 +1,47 @@
+package cpi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/end"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeRepo struct {
+	rows []CPI
+}
+
+func (f *fakeRepo) Get(_ context.Context, id domain.ID) (domain.Table[CPI], error) {
+	return domain.NewTable(id, time.Time{}, f.rows), nil
+}
+
+func TestSelectorRepeatsUntilPublished(t *testing.T) {
+	ctx := context.Background()
+	january := time.Date(2022, time.January, 31, 0, 0, 0, 0, time.UTC)
+	february := time.Date(2022, time.February, 28, 0, 0, 0, 0, time.UTC)
+	day := domain.NewUpdateCompleted(end.ID, time.Date(2022, time.March, 16, 0, 0, 0, 0, time.UTC))
+
+	stored := fakeRepo{rows: []CPI{{Date: january}}}
+	selector := selector{repo: &stored}
+
+	ids, err := selector.Select(ctx, day)
+	assert.Nil(t, err)
+	assert.Empty(t, ids, "Обновление до расписания публикации")
+
+	ids, err = selector.Select(ctx, domain.NewUpdateCompleted(end.CPIID, february))
+	assert.Nil(t, err)
+	assert.Equal(t, []domain.ID{ID}, ids, "Нет обновления по расписанию")
+
+	ids, err = selector.Select(ctx, day)
+	assert.Nil(t, err)
+	assert.Equal(t, []domain.ID{ID}, ids, "Нет повтора до публикации ожидаемого месяца")
+
+	stored.rows = append(stored.rows, CPI{Date: february})
+
+	ids, err = selector.Select(ctx, day)
+	assert.Nil(t, err)
+	assert.Empty(t, ids, "Повтор после публикации ожидаемого месяца")
+}
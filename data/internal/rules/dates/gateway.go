@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/WLM1ke/gomoex"
 	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
 	"time"
 )
 
@@ -11,6 +12,11 @@ type gateway struct {
 	iss *gomoex.ISSClient
 }
 
+// Source - источник данных шлюза.
+func (g gateway) Source() string {
+	return template.SourceISS
+}
+
 func (g gateway) Get(ctx context.Context, table domain.Table[gomoex.Date], _ time.Time) ([]gomoex.Date, error) {
 	rows, err := g.iss.MarketDates(ctx, gomoex.EngineStock, gomoex.MarketShares)
 
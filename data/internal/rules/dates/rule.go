@@ -8,14 +8,13 @@ import (
 	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
 	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
 	"go.mongodb.org/mongo-driver/mongo"
-	"time"
 )
 
 const _group = "dates"
 
 var ID = domain.NewID(_group, _group)
 
-func New(logger *lgr.Logger, db *mongo.Database, iss *gomoex.ISSClient, timeout time.Duration) domain.Rule {
+func New(logger *lgr.Logger, db *mongo.Database, iss *gomoex.ISSClient, opts template.Options) domain.Rule {
 	return template.NewRule[gomoex.Date](
 		"DatesRule",
 		logger,
@@ -24,6 +23,6 @@ func New(logger *lgr.Logger, db *mongo.Database, iss *gomoex.ISSClient, timeout
 		gateway{iss: iss},
 		validator,
 		false,
-		template.EventCtxFuncWithTimeout(timeout),
+		opts,
 	)
 }
@@ -0,0 +1,2 @@
+// Package dividends содержит правило обновления информации о дивидендах отдельных бумаг.
+package dividends
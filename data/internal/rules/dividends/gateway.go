@@ -0,0 +1,58 @@
+package dividends
+
+import (
+	"context"
+	"github.com/WLM1ke/gomoex"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
+	"sort"
+	"time"
+)
+
+type gateway struct {
+	iss *gomoex.ISSClient
+}
+
+// Source - источник данных шлюза.
+func (g gateway) Source() string {
+	return template.SourceISS
+}
+
+func (g gateway) Get(ctx context.Context, table domain.Table[Dividend], _ time.Time) ([]Dividend, error) {
+	raw, err := g.iss.Dividends(ctx, string(table.Name()))
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]Dividend, 0, len(raw))
+
+	for _, div := range raw {
+		rows = append(rows, Dividend{
+			Date:     div.Date,
+			Value:    div.Dividend,
+			Currency: div.Currency,
+		})
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Date.Before(rows[j].Date) })
+
+	if equal(table.Rows(), rows) {
+		return nil, nil
+	}
+
+	return rows, nil
+}
+
+func equal(old, fresh []Dividend) bool {
+	if len(old) != len(fresh) {
+		return false
+	}
+
+	for n, row := range old {
+		if !row.Date.Equal(fresh[n].Date) || row.Value != fresh[n].Value || row.Currency != fresh[n].Currency {
+			return false
+		}
+	}
+
+	return true
+}
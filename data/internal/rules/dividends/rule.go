@@ -0,0 +1,43 @@
+package dividends
+
+import (
+	"github.com/WLM1ke/gomoex"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/status"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
+	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+const _group = "dividends"
+
+// Group - группа таблиц с дивидендами отдельных бумаг.
+const Group domain.Group = _group
+
+// ID - таблица с дивидендами заданной бумаги.
+func ID(ticker string) domain.ID {
+	return domain.NewID(_group, ticker)
+}
+
+// Dividend - информация о дивиденде с датой закрытия реестра.
+type Dividend struct {
+	Date     time.Time
+	Value    float64
+	Currency string
+}
+
+// New создает правило обновления дивидендов для бумаг, по которым ожидается закрытие реестра.
+func New(logger *lgr.Logger, db *mongo.Database, iss *gomoex.ISSClient, opts template.Options) domain.Rule {
+	return template.NewRule[Dividend](
+		"DividendsRule",
+		logger,
+		repo.NewMongo[Dividend](db),
+		selector{status: repo.NewMongo[status.DivStatus](db)},
+		gateway{iss: iss},
+		validator,
+		false,
+		opts,
+	)
+}
@@ -0,0 +1,44 @@
+package dividends
+
+import (
+	"context"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/status"
+)
+
+type selector struct {
+	status repo.Read[status.DivStatus]
+}
+
+func (s selector) Subscriptions() []domain.Subscription {
+	return []domain.Subscription{domain.Subscribe[domain.UpdateCompleted](status.ID)}
+}
+
+func (s selector) Produces() []domain.ID {
+	return []domain.ID{domain.GroupID(Group)}
+}
+
+func (s selector) Select(ctx context.Context, event domain.Event) (ids []domain.ID, err error) {
+	switch selected := event.(type) {
+	case domain.UpdateCompleted:
+		if selected.ID() == status.ID {
+			table, err := s.status.Get(ctx, status.ID)
+			if err != nil {
+				return nil, err
+			}
+
+			// Строки таблицы статуса отсортированы по тикерам, поэтому достаточно пропускать повторы подряд
+			prev := ""
+
+			for _, row := range table.Rows() {
+				if row.Ticker != prev {
+					ids = append(ids, ID(row.Ticker))
+					prev = row.Ticker
+				}
+			}
+		}
+	}
+
+	return ids, err
+}
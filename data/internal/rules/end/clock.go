@@ -0,0 +1,30 @@
+package end
+
+import "time"
+
+// Clock - источник текущего времени и периодических сигналов для проверки расписаний.
+//
+// Позволяет в тестах управлять временем, не дожидаясь его реального течения.
+type Clock interface {
+	// Now - текущее время.
+	Now() time.Time
+	// Ticker возвращает канал периодических сигналов и функцию их остановки.
+	Ticker(d time.Duration) (<-chan time.Time, func())
+}
+
+type systemClock struct{}
+
+// SystemClock - системные часы.
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+func (c systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (c systemClock) Ticker(d time.Duration) (<-chan time.Time, func()) {
+	ticker := time.NewTicker(d)
+
+	return ticker.C, ticker.Stop
+}
@@ -1,8 +1,9 @@
-// Package end содержит правило, порождающее события через определенные промежутки времени.
+// Package end содержит правило, порождающее события о публикации новых данных внешними источниками.
 package end
 
 import (
 	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
 	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
 	"time"
 )
@@ -10,67 +11,121 @@ import (
 const (
 	_tickerDuration = time.Minute
 	_group          = "day_ended"
-	// Информация о торгах публикуется на MOEX ISS в 0:45 по московскому времени на следующий день.
-	_issTZ     = "Europe/Moscow"
-	_issHour   = 0
-	_issMinute = 45
 )
 
-var ID = domain.NewID(_group, _group)
+var (
+	// ID - окончание торгового дня и публикация его итогов на MOEX ISS.
+	ID = domain.NewID(_group, _group)
+	// CPIID - публикация Росстатом индекса потребительских цен за очередной месяц.
+	CPIID = domain.NewID(_group, "cpi")
+)
 
 // Rule - правило, сообщающее о возможном появлении новых данных.
 //
-// Данные события могут использоваться для запуска некоторых действий на регулярной основе
+// Раз в минуту проверяет расписания публикации данных и сообщает о наступлении очередной публикации. Данные события
+// могут использоваться для запуска некоторых действий на регулярной основе. По запросу на повторное обновление,
+// адресованному правилу, немедленно сообщает о публикации на дату запроса.
 type Rule struct {
-	logger *lgr.Logger
+	logger    *lgr.Logger
+	clock     Clock
+	ctxFunc   template.EventCtxFunc
+	schedules []Schedule
 
-	last time.Time
-	loc  *time.Location
+	last map[domain.ID]time.Time
 }
 
-func New(logger *lgr.Logger) *Rule {
-	loc, err := time.LoadLocation(_issTZ)
-	if err != nil {
-		panic("can't load time zone")
+// New создает правило, проверяющее расписания по заданным часам.
+func New(logger *lgr.Logger, clock Clock, ctxFunc template.EventCtxFunc, schedules ...Schedule) *Rule {
+	return &Rule{
+		logger:    logger,
+		clock:     clock,
+		ctxFunc:   ctxFunc,
+		schedules: schedules,
+		last:      make(map[domain.ID]time.Time),
 	}
+}
+
+// Subscriptions - правило обрабатывает только запросы на повторное обновление и отслеживает закрытие входящего канала.
+func (r *Rule) Subscriptions() []domain.Subscription {
+	return []domain.Subscription{domain.SubscribeAll[domain.RetryRequested]()}
+}
 
-	return &Rule{logger: logger, loc: loc}
+// Name - название правила.
+func (r *Rule) Name() string {
+	return "DayEndedRule"
+}
+
+// Produces - таблицы, которые обновляет правило.
+func (r *Rule) Produces() []domain.ID {
+	ids := make([]domain.ID, 0, len(r.schedules))
+	for _, schedule := range r.schedules {
+		ids = append(ids, schedule.ID())
+	}
+
+	return ids
 }
 
 func (r *Rule) Activate(in <-chan domain.Event, out chan<- domain.Event) {
 	r.logger.Infof("DayEndedRule: started")
 	defer r.logger.Infof("DayEndedRule: stopped")
 
-	ticker := time.NewTicker(_tickerDuration)
-	defer ticker.Stop()
+	ticks, stop := r.clock.Ticker(_tickerDuration)
+	defer stop()
 
 	r.sendIfStart(out)
 
 	for {
 		select {
-		case _, ok := <-in:
+		case event, ok := <-in:
 			if !ok {
 				return
 			}
-		case <-ticker.C:
+
+			r.sendIfRequested(out, event)
+		case <-ticks:
 			r.sendIfStart(out)
 		}
 	}
 }
 
 func (r *Rule) sendIfStart(out chan<- domain.Event) {
-	now := time.Now().In(r.loc)
-	end := time.Date(now.Year(), now.Month(), now.Day(), _issHour, _issMinute, 0, 0, r.loc)
+	ctx, cancel := r.ctxFunc()
+	defer cancel()
+
+	now := r.clock.Now()
+
+	for _, schedule := range r.schedules {
+		lastNew, err := schedule.Last(ctx, now)
+		if err != nil {
+			r.logger.Warnf("DayEndedRule: can't check schedule for %s -> %s", schedule.ID(), err)
 
-	delta := 2
-	if end.Before(now) {
-		delta = 1
+			continue
+		}
+
+		if r.last[schedule.ID()].Before(lastNew) {
+			r.last[schedule.ID()] = lastNew
+
+			out <- domain.NewUpdateCompleted(schedule.ID(), lastNew)
+		}
+	}
+}
+
+// sendIfRequested сообщает о публикации данных по адресованному правилу запросу на повторное обновление.
+//
+// Дата последней публикации не изменяется, поэтому очередная публикация по расписанию не пропускается.
+func (r *Rule) sendIfRequested(out chan<- domain.Event, event domain.Event) {
+	retry, ok := event.(domain.RetryRequested)
+	if !ok || retry.Rule() != r.Name() {
+		return
 	}
 
-	lastNew := time.Date(now.Year(), now.Month(), now.Day()-delta, 0, 0, 0, 0, time.UTC)
-	if r.last.Before(lastNew) {
-		r.last = lastNew
+	for _, schedule := range r.schedules {
+		if schedule.ID() == retry.ID() {
+			r.logger.Infof("DayEndedRule: %s requested %s", retry, retry.Trace())
 
-		out <- domain.NewUpdateCompleted(ID, lastNew)
+			out <- domain.CausedBy(retry, domain.NewUpdateCompleted(retry.ID(), retry.Date()))
+
+			return
+		}
 	}
 }
@@ -0,0 +1,148 @@
+package end
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
+	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock - часы, время которых задается тестом при каждой проверке расписаний.
+type fakeClock struct {
+	now   chan time.Time
+	ticks chan time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return <-c.now
+}
+
+func (c *fakeClock) Ticker(_ time.Duration) (<-chan time.Time, func()) {
+	return c.ticks, func() {}
+}
+
+// tick посылает сигнал и сообщает правилу текущее время.
+func (c *fakeClock) tick(now time.Time) {
+	c.ticks <- now
+	c.now <- now
+}
+
+type weekdays struct{}
+
+func (c weekdays) TradingDay(_ context.Context, date time.Time) (bool, error) {
+	return date.Weekday() != time.Saturday && date.Weekday() != time.Sunday, nil
+}
+
+func day(month time.Month, n int) time.Time {
+	return time.Date(2022, month, n, 0, 0, 0, 0, time.UTC)
+}
+
+func TestRule(t *testing.T) {
+	schedules, err := NewSchedules(Settings{TZ: "Europe/Moscow", ISS: "00:45", CPIDay: 15, CPI: "19:00"}, weekdays{})
+	assert.Nil(t, err)
+
+	msk, err := time.LoadLocation("Europe/Moscow")
+	assert.Nil(t, err)
+
+	at := func(month time.Month, n, hour, minute int) time.Time {
+		return time.Date(2022, month, n, hour, minute, 0, 0, msk)
+	}
+
+	clock := &fakeClock{now: make(chan time.Time), ticks: make(chan time.Time)}
+	rule := New(lgr.NoOp(), clock, template.EventCtxFuncWithTimeout(context.Background(), time.Second), schedules...)
+
+	in := make(chan domain.Event)
+	out := make(chan domain.Event, 10)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		rule.Activate(in, out)
+	}()
+
+	// Проверка при запуске - итоги понедельника и индекс цен за январь
+	clock.now <- at(time.March, 1, 1, 0)
+	// Суббота - итоги пятницы
+	clock.tick(at(time.March, 5, 0, 45))
+	// Понедельник до публикации и вторник - выходные пропускаются
+	clock.tick(at(time.March, 7, 0, 44))
+	clock.tick(at(time.March, 8, 0, 0))
+	// Вторник после публикации - итоги понедельника и индекс цен за февраль
+	clock.tick(at(time.March, 8, 0, 45))
+	clock.tick(at(time.March, 15, 19, 0))
+
+	close(in)
+	<-done
+	close(out)
+
+	var got []domain.Event
+	for event := range out {
+		got = append(got, event)
+	}
+
+	want := []struct {
+		id   domain.ID
+		date time.Time
+	}{
+		{ID, day(time.February, 28)},
+		{CPIID, day(time.January, 31)},
+		{ID, day(time.March, 4)},
+		{ID, day(time.March, 7)},
+		{ID, day(time.March, 14)},
+		{CPIID, day(time.February, 28)},
+	}
+
+	assert.Equal(t, len(want), len(got))
+
+	for i := range want {
+		assert.Equal(t, want[i].id, got[i].ID())
+		assert.Equal(t, want[i].date, got[i].Date())
+	}
+}
+
+func TestRuleRetryRequested(t *testing.T) {
+	schedules, err := NewSchedules(Settings{TZ: "Europe/Moscow", ISS: "00:45", CPIDay: 15, CPI: "19:00"}, weekdays{})
+	assert.Nil(t, err)
+
+	clock := &fakeClock{now: make(chan time.Time), ticks: make(chan time.Time)}
+	rule := New(lgr.NoOp(), clock, template.EventCtxFuncWithTimeout(context.Background(), time.Second), schedules...)
+
+	in := make(chan domain.Event)
+	out := make(chan domain.Event, 10)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		rule.Activate(in, out)
+	}()
+
+	clock.now <- time.Date(2022, time.March, 1, 0, 0, 0, 0, time.UTC)
+	<-out
+	<-out
+
+	retry := domain.NewRetryRequested(ID, day(time.March, 4), rule.Name())
+
+	in <- retry
+	in <- domain.NewRetryRequested(ID, day(time.March, 4), "QuotesRule")
+
+	close(in)
+	<-done
+	close(out)
+
+	var got []domain.Event
+	for event := range out {
+		got = append(got, event)
+	}
+
+	assert.Equal(t, 1, len(got), "Некорректное количество событий")
+	assert.IsType(t, domain.UpdateCompleted{}, got[0], "Некорректный тип события")
+	assert.Equal(t, ID, got[0].ID())
+	assert.Equal(t, day(time.March, 4), got[0].Date())
+	assert.Equal(t, retry.Trace().Correlation, got[0].Trace().Correlation, "Событие вне цепочки запроса")
+}
@@ -0,0 +1,170 @@
+package end
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"time"
+)
+
+const (
+	_timeFormat = "15:04"
+	// Максимальная продолжительность подряд идущих неторговых дней
+	_maxNonTradingDays = 31
+)
+
+// ErrSchedule - некорректное описание расписания публикации данных.
+var ErrSchedule = errors.New("bad schedule")
+
+// Schedule - расписание публикации данных внешним источником.
+type Schedule interface {
+	// ID - таблица, об обновлении которой сообщается после публикации новых данных.
+	ID() domain.ID
+	// Last - дата последних данных, которые должны быть опубликованы к моменту now.
+	Last(ctx context.Context, now time.Time) (time.Time, error)
+}
+
+// Calendar - торговый календарь.
+type Calendar interface {
+	// TradingDay проверяет, проводились ли торги в заданную дату.
+	TradingDay(ctx context.Context, date time.Time) (bool, error)
+}
+
+// Settings - настройки расписаний публикации данных.
+type Settings struct {
+	// TZ - часовой пояс, в котором заданы время и даты публикации.
+	TZ string
+	// ISS - время публикации итогов торгов на MOEX ISS на следующий день в формате 15:04.
+	ISS string
+	// CPIDay - день месяца публикации Росстатом индекса потребительских цен за предыдущий месяц.
+	CPIDay int
+	// CPI - время публикации индекса потребительских цен в формате 15:04.
+	CPI string
+	// Holidays - праздничные дни MOEX в формате 2006-01-02 в дополнение к выходным.
+	Holidays []string
+	// Workdays - выходные дни, объявленные MOEX рабочими, в формате 2006-01-02.
+	Workdays []string
+}
+
+// NewSchedules создает расписания публикации итогов торгов на MOEX ISS и индекса потребительских цен.
+//
+// Итоги торгов публикуются только для торговых дней в соответствии с календарем. Первым возвращается расписание
+// публикации итогов торгов.
+func NewSchedules(settings Settings, calendar Calendar) ([]Schedule, error) {
+	loc, err := time.LoadLocation(settings.TZ)
+	if err != nil {
+		return nil, fmt.Errorf("%w: can't load time zone %s -> %s", ErrSchedule, settings.TZ, err)
+	}
+
+	iss, err := NewDaily(ID, loc, settings.ISS, calendar)
+	if err != nil {
+		return nil, err
+	}
+
+	cpi, err := NewMonthly(CPIID, loc, settings.CPIDay, settings.CPI)
+	if err != nil {
+		return nil, err
+	}
+
+	return []Schedule{iss, cpi}, nil
+}
+
+func parseTime(raw string) (time.Time, error) {
+	at, err := time.Parse(_timeFormat, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: can't parse time %s -> %s", ErrSchedule, raw, err)
+	}
+
+	return at, nil
+}
+
+// daily - ежедневная публикация данных за предыдущий торговый день.
+type daily struct {
+	id       domain.ID
+	loc      *time.Location
+	at       time.Time
+	calendar Calendar
+}
+
+// NewDaily создает расписание ежедневной публикации в момент at данных за предыдущий день.
+//
+// Если календарь задан, данные публикуются только за торговые дни.
+func NewDaily(id domain.ID, loc *time.Location, at string, calendar Calendar) (Schedule, error) {
+	parsed, err := parseTime(at)
+	if err != nil {
+		return nil, err
+	}
+
+	return daily{id: id, loc: loc, at: parsed, calendar: calendar}, nil
+}
+
+func (s daily) ID() domain.ID {
+	return s.id
+}
+
+func (s daily) Last(ctx context.Context, now time.Time) (time.Time, error) {
+	now = now.In(s.loc)
+	published := time.Date(now.Year(), now.Month(), now.Day(), s.at.Hour(), s.at.Minute(), 0, 0, s.loc)
+
+	delta := 2
+	if !now.Before(published) {
+		delta = 1
+	}
+
+	date := time.Date(now.Year(), now.Month(), now.Day()-delta, 0, 0, 0, 0, time.UTC)
+	if s.calendar == nil {
+		return date, nil
+	}
+
+	for i := 0; i < _maxNonTradingDays; i++ {
+		trading, err := s.calendar.TradingDay(ctx, date)
+		if err != nil || trading {
+			return date, err
+		}
+
+		date = date.AddDate(0, 0, -1)
+	}
+
+	return time.Time{}, fmt.Errorf("%w: no trading days before %s", ErrSchedule, now.Format(time.RFC3339))
+}
+
+// monthly - ежемесячная публикация данных за предыдущий месяц.
+type monthly struct {
+	id  domain.ID
+	loc *time.Location
+	day int
+	at  time.Time
+}
+
+// NewMonthly создает расписание публикации в заданный день месяца и момент at данных за предыдущий месяц.
+func NewMonthly(id domain.ID, loc *time.Location, day int, at string) (Schedule, error) {
+	if day < 1 || day > 28 {
+		return nil, fmt.Errorf("%w: day of month %d not in [1, 28]", ErrSchedule, day)
+	}
+
+	parsed, err := parseTime(at)
+	if err != nil {
+		return nil, err
+	}
+
+	return monthly{id: id, loc: loc, day: day, at: parsed}, nil
+}
+
+func (s monthly) ID() domain.ID {
+	return s.id
+}
+
+// Last - последний день месяца, данные за который опубликованы.
+func (s monthly) Last(_ context.Context, now time.Time) (time.Time, error) {
+	now = now.In(s.loc)
+	published := time.Date(now.Year(), now.Month(), s.day, s.at.Hour(), s.at.Minute(), 0, 0, s.loc)
+
+	month := now.Month()
+	if now.Before(published) {
+		month--
+	}
+
+	// Нулевой день месяца - последний день предыдущего месяца
+	return time.Date(now.Year(), month, 0, 0, 0, 0, 0, time.UTC), nil
+}
@@ -7,7 +7,6 @@ import (
 	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
 	"github.com/WLM1ke/poptimizer/data/pkg/client"
 	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
-	"time"
 )
 
 // Rule - правило обработки ошибок.
@@ -18,32 +17,59 @@ type Rule struct {
 }
 
 // New создает правило обработки событий-ошибок.
-func New(logger *lgr.Logger, telegram *client.Telegram, timeout time.Duration) *Rule {
-	return &Rule{logger: logger, telegram: telegram, ctxFunc: template.EventCtxFuncWithTimeout(timeout)}
+func New(logger *lgr.Logger, telegram *client.Telegram, ctxFunc template.EventCtxFunc) *Rule {
+	return &Rule{logger: logger, telegram: telegram, ctxFunc: ctxFunc}
+}
+
+// Subscriptions - ошибки и события, требующие внимания.
+func (r *Rule) Subscriptions() []domain.Subscription {
+	return []domain.Subscription{
+		domain.SubscribeAll[domain.ErrorOccurred](),
+		domain.SubscribeAll[domain.GatewayFailed](),
+		domain.SubscribeAll[domain.ValidationFailed](),
+		domain.SubscribeAll[domain.RevisionDetected](),
+		domain.SubscribeAll[domain.ListingChanged](),
+		domain.SubscribeAll[domain.SourceUnavailable](),
+		domain.SubscribeAll[domain.SourceRecovered](),
+	}
+}
+
+// Name - название правила.
+func (r *Rule) Name() string {
+	return "ErrorRule"
+}
+
+// Produces - правило не обновляет таблицы.
+func (r *Rule) Produces() []domain.ID {
+	return nil
 }
 
 // Activate - активирует правило.
 //
-// Пишет в лог предупреждения и посылает сообщения в Telegram.
+// Пишет в лог предупреждения и посылает сообщения в Telegram. Помимо ошибок сообщает о пересмотре исторических данных
+// и изменениях листинга, так как они требуют проверки корректности новых данных, а также о недоступности и
+// восстановлении внешних источников данных.
 func (r *Rule) Activate(in <-chan domain.Event, _ chan<- domain.Event) {
 	r.logger.Infof("ErrorRule: started")
 	defer r.logger.Infof("ErrorRule: stopped")
 
 	for event := range in {
-		event, ok := event.(domain.ErrorOccurred)
-		if ok {
+		switch event.(type) {
+		case domain.ErrorOccurred, domain.GatewayFailed, domain.ValidationFailed,
+			domain.RevisionDetected, domain.ListingChanged,
+			domain.SourceUnavailable, domain.SourceRecovered:
 			r.process(event)
 		}
 	}
 }
 
-func (r *Rule) process(event domain.ErrorOccurred) {
-	r.logger.Warnf("ErrorRule: %s", event)
+func (r *Rule) process(event domain.Event) {
+	r.logger.Warnf("ErrorRule: %s %s", event, event.Trace())
 
 	ctx, cancel := r.ctxFunc()
 	defer cancel()
 
-	err := r.telegram.Send(ctx, fmt.Sprint(event))
+	err := r.telegram.Send(ctx, fmt.Sprintf("%s %s", event, event.Trace()))
 	if err != nil {
 		r.logger.Warnf("ErrorRule: can't send to telegram %s -> %s", event, err)
 	}
@@ -0,0 +1,173 @@
+package indexes
+
+import (
+	"context"
+	"fmt"
+	"github.com/WLM1ke/gomoex"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/end"
+	"go.mongodb.org/mongo-driver/mongo"
+	"sync"
+	"time"
+)
+
+const (
+	_dayFormat = "2006-01-02"
+	// _calendarTTL - период обновления торговых дней из таблицы индекса МосБиржи
+	_calendarTTL = time.Hour
+)
+
+// Calendar - торговый календарь рынка MOEX.
+//
+// Календарь фондового рынка строится по датам котировок индекса МосБиржи, который рассчитывается каждый торговый
+// день, в том числе в рабочие выходные. Для дат после последней загруженной котировки торговыми считаются будни,
+// кроме праздничных дней, и выходные, объявленные рабочими. Праздники и рабочие выходные MOEX публикует ежегодно,
+// а их настройка влияет только на даты, котировки индекса за которые еще не загружены.
+//
+// Для рынков без ряда, рассчитываемого во все торговые дни, календарь строится только по настройкам праздников
+// и рабочих выходных.
+type Calendar struct {
+	repo repo.Read[gomoex.Quote]
+	// reference - торговые дни определяются по котировкам индекса МосБиржи
+	reference bool
+	holidays  map[time.Time]bool
+	workdays  map[time.Time]bool
+
+	lock   sync.Mutex
+	loaded time.Time
+	days   map[time.Time]bool
+	last   time.Time
+}
+
+// NewCalendar создает торговый календарь фондового рынка с праздничными днями и рабочими выходными в формате
+// 2006-01-02.
+func NewCalendar(db *mongo.Database, holidays, workdays []string) (*Calendar, error) {
+	calendar, err := newSettingsCalendar(holidays, workdays)
+	if err != nil {
+		return nil, err
+	}
+
+	calendar.repo = repo.NewMongo[gomoex.Quote](db)
+	calendar.reference = true
+
+	return calendar, nil
+}
+
+// NewCurrencyCalendar создает торговый календарь валютного рынка с праздничными днями и рабочими выходными в формате
+// 2006-01-02.
+//
+// Валютный рынок работает и в часть праздничных дней фондового рынка, поэтому его календарь задается отдельно.
+func NewCurrencyCalendar(holidays, workdays []string) (*Calendar, error) {
+	return newSettingsCalendar(holidays, workdays)
+}
+
+func newSettingsCalendar(holidays, workdays []string) (*Calendar, error) {
+	holidayDays, err := parseDays(holidays)
+	if err != nil {
+		return nil, err
+	}
+
+	workDays, err := parseDays(workdays)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Calendar{holidays: holidayDays, workdays: workDays}, nil
+}
+
+// WithoutReference - календарь с теми же праздниками и рабочими выходными, но без учета котировок индекса
+// МосБиржи, позволяющий проверить пропуски в самом индексе.
+func (c *Calendar) WithoutReference() *Calendar {
+	return &Calendar{holidays: c.holidays, workdays: c.workdays}
+}
+
+func parseDays(raw []string) (map[time.Time]bool, error) {
+	days := make(map[time.Time]bool, len(raw))
+
+	for _, day := range raw {
+		if day == "" {
+			continue
+		}
+
+		parsed, err := time.Parse(_dayFormat, day)
+		if err != nil {
+			return nil, fmt.Errorf("%w: can't parse day %s -> %s", end.ErrSchedule, day, err)
+		}
+
+		days[parsed] = true
+	}
+
+	return days, nil
+}
+
+// TradingDay проверяет, проводились ли торги в заданную дату.
+func (c *Calendar) TradingDay(ctx context.Context, date time.Time) (bool, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if err := c.load(ctx); err != nil {
+		return false, err
+	}
+
+	return c.tradingDay(date), nil
+}
+
+// Days - торговые дни с from по till включительно.
+func (c *Calendar) Days(ctx context.Context, from, till time.Time) ([]time.Time, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if err := c.load(ctx); err != nil {
+		return nil, err
+	}
+
+	var days []time.Time
+
+	for date := from; !date.After(till); date = date.AddDate(0, 0, 1) {
+		if c.tradingDay(date) {
+			days = append(days, date)
+		}
+	}
+
+	return days, nil
+}
+
+func (c *Calendar) tradingDay(date time.Time) bool {
+	if !c.last.IsZero() && !date.After(c.last) {
+		return c.days[date]
+	}
+
+	switch date.Weekday() {
+	case time.Saturday, time.Sunday:
+		return c.workdays[date]
+	default:
+		return !c.holidays[date]
+	}
+}
+
+// load загружает даты котировок индекса МосБиржи, если они не обновлялись дольше _calendarTTL.
+func (c *Calendar) load(ctx context.Context) error {
+	if !c.reference || c.days != nil && time.Since(c.loaded) < _calendarTTL {
+		return nil
+	}
+
+	table, err := c.repo.Get(ctx, IMOEX)
+	if err != nil {
+		return err
+	}
+
+	days := make(map[time.Time]bool, len(table.Rows()))
+	for _, row := range table.Rows() {
+		days[row.Date] = true
+	}
+
+	c.days = days
+	c.loaded = time.Now()
+	c.last = time.Time{}
+
+	if !table.IsEmpty() {
+		c.last = table.LastRow().Date
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package indexes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/WLM1ke/gomoex"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeQuotes struct {
+	rows []gomoex.Quote
+}
+
+func (f fakeQuotes) Get(_ context.Context, id domain.ID) (domain.Table[gomoex.Quote], error) {
+	return domain.NewTable(id, time.Time{}, f.rows), nil
+}
+
+func TestCalendar(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2022, time.March, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	// Котировки загружены по 9 марта, включая рабочую субботу 5 марта. Для последующих дат используются праздники и
+	// рабочие выходные из настроек
+	calendar, err := NewCalendar(nil, []string{"2022-03-14"}, []string{"2022-03-12"})
+	assert.Nil(t, err)
+
+	calendar.repo = fakeQuotes{rows: []gomoex.Quote{{Date: day(4)}, {Date: day(5)}, {Date: day(9)}}}
+
+	tbl := []struct {
+		date    time.Time
+		trading bool
+	}{
+		{day(4), true},
+		{day(5), true},
+		{day(6), false},
+		{day(7), false},
+		{day(8), false},
+		{day(9), true},
+		{day(10), true},
+		{day(11), true},
+		{day(12), true},
+		{day(13), false},
+		{day(14), false},
+		{day(15), true},
+	}
+
+	for _, testCase := range tbl {
+		trading, err := calendar.TradingDay(context.Background(), testCase.date)
+
+		assert.Nil(t, err)
+		assert.Equal(t, testCase.trading, trading, testCase.date.Format(_dayFormat))
+	}
+
+	_, err = NewCalendar(nil, []string{"08.03.2022"}, nil)
+	assert.NotNil(t, err, "Некорректный формат праздника")
+}
+
+func TestCalendarDays(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2022, time.March, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	calendar, err := NewCalendar(nil, nil, nil)
+	assert.Nil(t, err)
+
+	// Котировки индекса загружены по 9 марта без 8 марта, которое не указано в праздниках
+	calendar.repo = fakeQuotes{rows: []gomoex.Quote{{Date: day(4)}, {Date: day(7)}, {Date: day(9)}}}
+
+	days, err := calendar.Days(context.Background(), day(4), day(10))
+	assert.Nil(t, err)
+	assert.Equal(t, []time.Time{day(4), day(7), day(9), day(10)}, days, "Некорректные дни по котировкам индекса")
+
+	days, err = calendar.WithoutReference().Days(context.Background(), day(4), day(10))
+	assert.Nil(t, err)
+	assert.Equal(t, []time.Time{day(4), day(7), day(8), day(9), day(10)}, days, "Некорректные дни по настройкам")
+
+	currency, err := NewCurrencyCalendar(nil, []string{"2022-03-05"})
+	assert.Nil(t, err)
+
+	days, err = currency.Days(context.Background(), day(4), day(8))
+	assert.Nil(t, err)
+	assert.Equal(t, []time.Time{day(4), day(5), day(7), day(8)}, days, "Некорректные дни валютного рынка")
+}
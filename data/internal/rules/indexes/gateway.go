@@ -2,18 +2,32 @@ package indexes
 
 import (
 	"context"
+	"fmt"
 	"github.com/WLM1ke/gomoex"
 	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
 	"time"
 )
 
 const _format = `2006-01-02`
 
 type gateway struct {
-	iss *gomoex.ISSClient
+	iss  *gomoex.ISSClient
+	list repo.Read[Index]
+}
+
+// Source - источник данных шлюза.
+func (g gateway) Source() string {
+	return template.SourceISS
 }
 
 func (g gateway) Get(ctx context.Context, table domain.Table[gomoex.Quote], date time.Time) ([]gomoex.Quote, error) {
+	market, err := g.market(ctx, string(table.Name()))
+	if err != nil {
+		return nil, err
+	}
+
 	start := ""
 	if !table.IsEmpty() {
 		start = table.LastRow().Date.Format(_format)
@@ -24,7 +38,7 @@ func (g gateway) Get(ctx context.Context, table domain.Table[gomoex.Quote], date
 	rows, err := g.iss.MarketHistory(
 		ctx,
 		gomoex.EngineStock,
-		gomoex.MarketIndex,
+		market,
 		string(table.Name()),
 		start,
 		end,
@@ -35,3 +49,18 @@ func (g gateway) Get(ctx context.Context, table domain.Table[gomoex.Quote], date
 
 	return rows, nil
 }
+
+func (g gateway) market(ctx context.Context, ticker string) (string, error) {
+	table, err := g.list.Get(ctx, ListID)
+	if err != nil {
+		return "", err
+	}
+
+	for _, index := range table.Rows() {
+		if index.Ticker == ticker {
+			return index.Market, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: index %s not found in list", template.ErrRuleGateway, ticker)
+}
@@ -0,0 +1,127 @@
+package indexes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/WLM1ke/gomoex"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	_listGroup     = "index_list"
+	_listSeparator = ":"
+)
+
+// ListID - таблица со списком загружаемых индексов.
+var ListID = domain.NewID(_listGroup, _listGroup)
+
+// ErrIndexList некорректный список индексов в конфигурации.
+var ErrIndexList = errors.New("invalid index list")
+
+// markets - рынки фондового рынка MOEX ISS, на которых могут находиться индексы.
+var markets = map[string]bool{
+	gomoex.MarketIndex:         true,
+	gomoex.MarketBonds:         true,
+	gomoex.MarketShares:        true,
+	gomoex.MarketForeignShares: true,
+}
+
+// Index - индекс и рынок MOEX ISS, на котором он рассчитывается.
+type Index struct {
+	Ticker string
+	Market string
+}
+
+// listGateway формирует список индексов на основе конфигурации.
+//
+// Список возвращается только при его изменении по сравнению с сохраненным.
+type listGateway struct {
+	list []Index
+}
+
+func (g listGateway) Get(_ context.Context, table domain.Table[Index], _ time.Time) ([]Index, error) {
+	if equal(table.Rows(), g.list) {
+		return nil, nil
+	}
+
+	return g.list, nil
+}
+
+// parseList разбирает список индексов из конфигурации и сортирует его по тикерам.
+//
+// Индексы задаются в виде TICKER или TICKER:market, по умолчанию используется рынок индексов фондового рынка.
+func parseList(list []string) ([]Index, error) {
+	rows := make([]Index, 0, len(list))
+
+	for _, raw := range list {
+		index, err := parseIndex(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		rows = append(rows, index)
+	}
+
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Ticker < rows[j].Ticker })
+
+	for n := 1; n < len(rows); n++ {
+		if rows[n-1].Ticker == rows[n].Ticker {
+			return nil, fmt.Errorf("%w: duplicate index %s", ErrIndexList, rows[n].Ticker)
+		}
+	}
+
+	return rows, nil
+}
+
+// parseIndex разбирает описание индекса в формате TICKER или TICKER:market.
+func parseIndex(raw string) (Index, error) {
+	index := Index{Market: gomoex.MarketIndex}
+
+	switch parts := strings.Split(raw, _listSeparator); len(parts) {
+	case 1:
+		index.Ticker = parts[0]
+	case 2:
+		index.Ticker, index.Market = parts[0], parts[1]
+	default:
+		return Index{}, fmt.Errorf("%w: bad index description %s", ErrIndexList, raw)
+	}
+
+	if index.Ticker == "" || !markets[index.Market] {
+		return Index{}, fmt.Errorf("%w: bad index description %s", ErrIndexList, raw)
+	}
+
+	return index, nil
+}
+
+func equal(old, fresh []Index) bool {
+	if len(old) != len(fresh) {
+		return false
+	}
+
+	for n, row := range old {
+		if row != fresh[n] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func listValidator(_ domain.Table[Index], rows []Index) error {
+	prev := rows[0].Ticker
+	for _, row := range rows[1:] {
+		if prev < row.Ticker {
+			prev = row.Ticker
+			continue
+		}
+
+		return fmt.Errorf("%w: not increasing tickers %s and %s", template.ErrNewRowsValidation, prev, row.Ticker)
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package indexes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/WLM1ke/gomoex"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/dates"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseList(t *testing.T) {
+	testCases := []struct {
+		name string
+		list []string
+		rows []Index
+		err  error
+	}{
+		{
+			"рынок по умолчанию и сортировка",
+			[]string{"RVI", "MCFTRR", "IMOEX"},
+			[]Index{
+				{Ticker: "IMOEX", Market: gomoex.MarketIndex},
+				{Ticker: "MCFTRR", Market: gomoex.MarketIndex},
+				{Ticker: "RVI", Market: gomoex.MarketIndex},
+			},
+			nil,
+		},
+		{
+			"явный рынок",
+			[]string{"IMOEX", "RGBITR:bonds"},
+			[]Index{{Ticker: "IMOEX", Market: gomoex.MarketIndex}, {Ticker: "RGBITR", Market: gomoex.MarketBonds}},
+			nil,
+		},
+		{"лишняя часть", []string{"A:index:c"}, nil, ErrIndexList},
+		{"неизвестный рынок", []string{"IMOEX:futures"}, nil, ErrIndexList},
+		{"пустой тикер", []string{":index"}, nil, ErrIndexList},
+		{"повтор", []string{"IMOEX", "IMOEX:index"}, nil, ErrIndexList},
+	}
+
+	for _, testCase := range testCases {
+		rows, err := parseList(testCase.list)
+
+		assert.ErrorIs(t, err, testCase.err, testCase.name)
+		assert.Equal(t, testCase.rows, rows, testCase.name)
+	}
+}
+
+func TestListGateway(t *testing.T) {
+	date := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	stored := domain.NewTable(ListID, date, []Index{
+		{Ticker: "IMOEX", Market: gomoex.MarketIndex},
+		{Ticker: "RVI", Market: gomoex.MarketIndex},
+	})
+
+	rows, err := listGateway{list: stored.Rows()}.Get(context.Background(), stored, date)
+	assert.Nil(t, err)
+	assert.Nil(t, rows, "Список не изменился")
+
+	list, err := parseList([]string{"IMOEX", "RGBITR:bonds"})
+	assert.Nil(t, err)
+
+	rows, err = listGateway{list: list}.Get(context.Background(), stored, date)
+	assert.Nil(t, err)
+	assert.Equal(t, list, rows, "Изменившийся список")
+}
+
+func TestNewListRejectsBadConfig(t *testing.T) {
+	_, err := NewList(nil, nil, []string{"IMOEX:futures"}, template.Options{})
+
+	assert.ErrorIs(t, err, ErrIndexList)
+}
+
+func TestListValidator(t *testing.T) {
+	empty := domain.NewEmptyTable[Index](ListID)
+
+	assert.Nil(t, listValidator(empty, []Index{{Ticker: "IMOEX"}, {Ticker: "RVI"}}))
+
+	err := listValidator(empty, []Index{{Ticker: "IMOEX"}, {Ticker: "RVI"}, {Ticker: "RVI"}})
+	assert.ErrorIs(t, err, template.ErrNewRowsValidation)
+	assert.Contains(t, err.Error(), "RVI and RVI", "В ошибке нет обоих тикеров")
+}
+
+type fakeList struct {
+	rows []Index
+}
+
+func (f fakeList) Get(_ context.Context, id domain.ID) (domain.Table[Index], error) {
+	return domain.NewTable(id, time.Time{}, f.rows), nil
+}
+
+func TestSelectorUpdatesOnceAfterListCheck(t *testing.T) {
+	date := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := selector{list: fakeList{rows: []Index{{Ticker: "IMOEX"}, {Ticker: "RVI"}}}}
+	all := []domain.ID{IMOEX, domain.NewID(_group, "RVI")}
+
+	checked := domain.NewUpdateCompleted(ListID, date)
+
+	testCases := []struct {
+		name  string
+		event domain.Event
+		ids   []domain.ID
+	}{
+		{"новый день", domain.NewUpdateCompleted(dates.ID, date), nil},
+		{"список изменился", checked, all},
+		{"список не изменился", domain.NewUpdateSkipped(checked), all},
+	}
+
+	for _, testCase := range testCases {
+		ids, err := s.Select(context.Background(), testCase.event)
+
+		assert.Nil(t, err, testCase.name)
+		assert.Equal(t, testCase.ids, ids, testCase.name)
+	}
+}
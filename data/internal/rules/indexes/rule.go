@@ -4,21 +4,46 @@ import (
 	"github.com/WLM1ke/gomoex"
 	"github.com/WLM1ke/poptimizer/data/internal/domain"
 	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/dates"
 	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
 	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
 	"go.mongodb.org/mongo-driver/mongo"
-	"time"
 )
 
-func New(logger *lgr.Logger, db *mongo.Database, iss *gomoex.ISSClient, timeout time.Duration) domain.Rule {
+// New создает правило обновления котировок индексов из таблицы со списком индексов.
+func New(logger *lgr.Logger, db *mongo.Database, iss *gomoex.ISSClient, opts template.Options) domain.Rule {
+	list := repo.NewMongo[Index](db)
+
 	return template.NewRule[gomoex.Quote](
 		"IndexesRule",
 		logger,
 		repo.NewMongo[gomoex.Quote](db),
-		selector{},
-		gateway{iss: iss},
+		selector{list: list},
+		gateway{iss: iss, list: list},
 		validator,
 		true,
-		template.EventCtxFuncWithTimeout(timeout),
+		opts,
 	)
 }
+
+// NewList создает правило обновления таблицы со списком индексов на основе конфигурации.
+//
+// Изменение списка приводит к немедленной загрузке котировок всех индексов из него. Список разбирается при создании
+// правила, поэтому ошибки конфигурации обнаруживаются при запуске.
+func NewList(logger *lgr.Logger, db *mongo.Database, list []string, opts template.Options) (domain.Rule, error) {
+	rows, err := parseList(list)
+	if err != nil {
+		return nil, err
+	}
+
+	return template.NewRule[Index](
+		"IndexListRule",
+		logger,
+		repo.NewMongo[Index](db),
+		template.NewSelectOnTableUpdate(dates.ID, ListID),
+		listGateway{list: rows},
+		listValidator,
+		false,
+		opts,
+	), nil
+}
@@ -3,29 +3,48 @@ package indexes
 import (
 	"context"
 	"github.com/WLM1ke/poptimizer/data/internal/domain"
-	"github.com/WLM1ke/poptimizer/data/internal/rules/dates"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
 )
 
 const _group = "indexes"
 
-var indexes = []string{
-	`MCFTRR`,
-	`MEOGTRR`,
-	`IMOEX`,
-	`RVI`,
-}
+// Group - группа таблиц с котировками индексов.
+const Group domain.Group = _group
+
+// IMOEX - таблица индекса МосБиржи, который рассчитывается каждый торговый день фондового рынка.
+var IMOEX = domain.NewID(_group, "IMOEX")
 
+// selector выбирает все индексы из списка после его ежедневной проверки.
+//
+// Список проверяется при начале нового дня, поэтому котировки индексов обновляются один раз после проверки - как при
+// изменении списка, так и при пропуске его обновления.
 type selector struct {
+	list repo.Read[Index]
+}
+
+func (s selector) Subscriptions() []domain.Subscription {
+	return []domain.Subscription{
+		domain.Subscribe[domain.UpdateCompleted](ListID),
+		domain.Subscribe[domain.UpdateSkipped](ListID),
+	}
+}
+
+func (s selector) Produces() []domain.ID {
+	return []domain.ID{domain.GroupID(Group)}
 }
 
-func (s selector) Select(_ context.Context, event domain.Event) (ids []domain.ID, err error) {
-	switch selected := event.(type) {
-	case domain.UpdateCompleted:
-		if selected.ID() == dates.ID {
-			for _, index := range indexes {
-				ids = append(ids, domain.NewID(_group, index))
+func (s selector) Select(ctx context.Context, event domain.Event) (ids []domain.ID, err error) {
+	switch event.(type) {
+	case domain.UpdateCompleted, domain.UpdateSkipped:
+		if event.ID() == ListID {
+			table, err := s.list.Get(ctx, ListID)
+			if err != nil {
+				return nil, err
 			}
 
+			for _, index := range table.Rows() {
+				ids = append(ids, domain.NewID(_group, index.Ticker))
+			}
 		}
 	}
 
@@ -22,7 +22,7 @@ func validator(table domain.Table[gomoex.Quote], rows []gomoex.Quote) error {
 		return nil
 	}
 
-	if table.LastRow() != rows[0] {
+	if !table.LastRow().Date.Equal(rows[0].Date) {
 		return fmt.Errorf(
 			"%w: old rows %+v not match new %+v",
 			template.ErrNewRowsValidation,
@@ -30,5 +30,13 @@ func validator(table domain.Table[gomoex.Quote], rows []gomoex.Quote) error {
 			rows[0])
 	}
 
+	if table.LastRow() != rows[0] {
+		return fmt.Errorf(
+			"%w: old rows %+v not match new %+v",
+			template.ErrHistoryRevision,
+			table.LastRow(),
+			rows[0])
+	}
+
 	return nil
 }
@@ -0,0 +1,2 @@
+// Package liquidity содержит правило расчета медианного оборота бумаг для отбора ликвидных инструментов.
+package liquidity
@@ -0,0 +1,246 @@
+package liquidity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/WLM1ke/gomoex"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/quotes"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/securities"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
+	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"go.mongodb.org/mongo-driver/mongo"
+	"sort"
+	"time"
+)
+
+const _group = "liquidity"
+
+var ID = domain.NewID(_group, _group)
+
+// Liquidity - медианный дневной оборот бумаги за несколько периодов, рассчитанный на дату последней котировки.
+type Liquidity struct {
+	Ticker   string
+	Date     time.Time
+	Turnover []Turnover
+}
+
+// Turnover - медианный дневной оборот в рублях за заданное количество последних торговых дней.
+type Turnover struct {
+	Days   int
+	Median float64
+}
+
+// ErrWindows - некорректные окна для расчета медианного оборота.
+var ErrWindows = errors.New("invalid liquidity windows")
+
+// _batchDelay - пауза в обновлениях котировок, после которой накопленные обновления записываются в таблицу.
+const _batchDelay = 10 * time.Second
+
+// Rule - правило пересчета ликвидности бумаг после обновления их котировок.
+//
+// Все бумаги хранятся в одной таблице, отсортированной по тикерам, поэтому обновления котировок накапливаются и
+// записываются одной перезаписью таблицы, когда их поток затихает. При перезаписи удаляются бумаги, которых больше нет
+// в таблице securities.
+type Rule struct {
+	logger     *lgr.Logger
+	repo       repo.ReadWrite[Liquidity]
+	quotes     repo.Read[gomoex.Candle]
+	securities repo.Read[gomoex.Security]
+	windows    []int
+	delay      time.Duration
+	ctxFunc    template.EventCtxFunc
+}
+
+// New создает правило расчета медианного оборота для заданных окон в торговых днях.
+func New(logger *lgr.Logger, db *mongo.Database, windows []int, ctxFunc template.EventCtxFunc) (*Rule, error) {
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("%w: no windows", ErrWindows)
+	}
+
+	for _, days := range windows {
+		if days <= 0 {
+			return nil, fmt.Errorf("%w: non positive window %d", ErrWindows, days)
+		}
+	}
+
+	return &Rule{
+		logger:     logger,
+		repo:       repo.NewMongo[Liquidity](db),
+		quotes:     repo.NewMongo[gomoex.Candle](db),
+		securities: repo.NewMongo[gomoex.Security](db),
+		windows:    windows,
+		delay:      _batchDelay,
+		ctxFunc:    ctxFunc,
+	}, nil
+}
+
+func (r *Rule) Subscriptions() []domain.Subscription {
+	return []domain.Subscription{domain.SubscribeGroup[domain.UpdateCompleted](quotes.Group)}
+}
+
+// Name - название правила.
+func (r *Rule) Name() string {
+	return "LiquidityRule"
+}
+
+// Produces - таблицы, которые обновляет правило.
+func (r *Rule) Produces() []domain.ID {
+	return []domain.ID{ID}
+}
+
+func (r *Rule) Activate(in <-chan domain.Event, out chan<- domain.Event) {
+	r.ActivateWithAck(in, out, func(domain.Event) {})
+}
+
+// ActivateWithAck подтверждает обработку обновлений котировок после записи накопленных обновлений в таблицу.
+func (r *Rule) ActivateWithAck(in <-chan domain.Event, out chan<- domain.Event, ack func(domain.Event)) {
+	r.logger.Infof("LiquidityRule: started")
+	defer r.logger.Infof("LiquidityRule: stopped")
+
+	template.ActivateBatched(
+		in,
+		out,
+		ack,
+		r.delay,
+		func(update domain.UpdateCompleted) bool { return update.Group() == quotes.Group },
+		r.process,
+	)
+}
+
+// process пересчитывает ликвидность бумаг из накопленных обновлений котировок и перезаписывает таблицу.
+func (r *Rule) process(batch []domain.UpdateCompleted) (events []domain.Event) {
+	ctx, cancel := r.ctxFunc()
+	defer cancel()
+
+	last := batch[len(batch)-1]
+
+	table, err := r.repo.Get(ctx, ID)
+	if err != nil {
+		return []domain.Event{domain.NewErrorOccurred(last, err)}
+	}
+
+	listed, err := r.listed(ctx)
+	if err != nil {
+		return []domain.Event{domain.NewErrorOccurred(last, err)}
+	}
+
+	rows := table.Rows()
+	date := table.Date()
+
+	for _, event := range template.Latest(batch) {
+		if event.Date().After(date) {
+			date = event.Date()
+		}
+
+		candles, err := r.quotes.Get(ctx, event.ID())
+		if err != nil {
+			events = append(events, domain.NewErrorOccurred(event, err))
+
+			continue
+		}
+
+		if candles.IsEmpty() {
+			continue
+		}
+
+		rows = upsert(rows, Liquidity{
+			Ticker:   string(event.Name()),
+			Date:     candles.LastRow().Begin,
+			Turnover: medians(candles.Rows(), r.windows),
+		})
+	}
+
+	update := domain.CausedBy(last, domain.NewUpdateCompleted(ID, date))
+	events = append(events, domain.NewUpdateStarted(update, r.Name()))
+
+	err = r.repo.Replace(ctx, domain.NewTable(ID, date, retain(rows, listed)).WithSource(r.Name()))
+	if err != nil {
+		return append(events, domain.NewErrorOccurred(update, err))
+	}
+
+	return append(events, update)
+}
+
+// listed - тикеры бумаг из таблицы securities.
+func (r *Rule) listed(ctx context.Context) (map[string]bool, error) {
+	table, err := r.securities.Get(ctx, securities.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	listed := make(map[string]bool, len(table.Rows()))
+	for _, sec := range table.Rows() {
+		listed[sec.Ticker] = true
+	}
+
+	return listed, nil
+}
+
+// retain удаляет строки бумаг, которых нет среди торгуемых.
+func retain(rows []Liquidity, listed map[string]bool) []Liquidity {
+	kept := rows[:0]
+
+	for _, row := range rows {
+		if listed[row.Ticker] {
+			kept = append(kept, row)
+		}
+	}
+
+	return kept
+}
+
+// upsert заменяет или добавляет строку с сохранением сортировки по тикерам.
+func upsert(rows []Liquidity, row Liquidity) []Liquidity {
+	n := sort.Search(len(rows), func(i int) bool { return rows[i].Ticker >= row.Ticker })
+
+	if n < len(rows) && rows[n].Ticker == row.Ticker {
+		rows[n] = row
+
+		return rows
+	}
+
+	rows = append(rows, Liquidity{})
+	copy(rows[n+1:], rows[n:])
+	rows[n] = row
+
+	return rows
+}
+
+// medians рассчитывает медианный оборот для каждого из окон по последним свечам.
+func medians(candles []gomoex.Candle, windows []int) []Turnover {
+	turnover := make([]Turnover, 0, len(windows))
+
+	for _, days := range windows {
+		start := len(candles) - days
+		if start < 0 {
+			start = 0
+		}
+
+		values := make([]float64, 0, len(candles)-start)
+		for _, candle := range candles[start:] {
+			values = append(values, candle.Value)
+		}
+
+		turnover = append(turnover, Turnover{Days: days, Median: median(values)})
+	}
+
+	return turnover
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sort.Float64s(values)
+
+	n := len(values) / 2
+	if len(values)%2 == 1 {
+		return values[n]
+	}
+
+	return (values[n-1] + values[n]) / 2
+}
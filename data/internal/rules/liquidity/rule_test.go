@@ -0,0 +1,148 @@
+package liquidity
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/WLM1ke/gomoex"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/quotes"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/securities"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
+	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMedians(t *testing.T) {
+	candles := []gomoex.Candle{{Value: 10}, {Value: 1}, {Value: 4}, {Value: 2}, {Value: 3}}
+
+	assert.Equal(
+		t,
+		[]Turnover{{Days: 2, Median: 2.5}, {Days: 3, Median: 3}, {Days: 10, Median: 3}},
+		medians(candles, []int{2, 3, 10}),
+		"Некорректный медианный оборот",
+	)
+}
+
+func TestUpsert(t *testing.T) {
+	rows := []Liquidity{{Ticker: "AKRN"}, {Ticker: "GAZP"}}
+
+	rows = upsert(rows, Liquidity{Ticker: "BANE"})
+	rows = upsert(rows, Liquidity{Ticker: "GAZP", Turnover: []Turnover{{Days: 1}}})
+
+	assert.Equal(
+		t,
+		[]Liquidity{{Ticker: "AKRN"}, {Ticker: "BANE"}, {Ticker: "GAZP", Turnover: []Turnover{{Days: 1}}}},
+		rows,
+		"Некорректное обновление таблицы",
+	)
+}
+
+type fakeRepo[R any] struct {
+	lock     sync.Mutex
+	tables   map[domain.ID]domain.Table[R]
+	replaced int
+}
+
+func newFakeRepo[R any](tables ...domain.Table[R]) *fakeRepo[R] {
+	repo := fakeRepo[R]{tables: make(map[domain.ID]domain.Table[R])}
+	for _, table := range tables {
+		repo.tables[table.ID()] = table
+	}
+
+	return &repo
+}
+
+func (f *fakeRepo[R]) Get(_ context.Context, id domain.ID) (domain.Table[R], error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if table, ok := f.tables[id]; ok {
+		return table, nil
+	}
+
+	return domain.NewEmptyTable[R](id), nil
+}
+
+func (f *fakeRepo[R]) Replace(_ context.Context, table domain.Table[R]) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.replaced++
+	f.tables[table.ID()] = table
+
+	return nil
+}
+
+func (f *fakeRepo[R]) Append(_ context.Context, _ domain.Table[R]) error {
+	panic("liquidity table is replaced only")
+}
+
+func TestNewValidatesWindows(t *testing.T) {
+	for _, windows := range [][]int{nil, {21, 0}, {-1}} {
+		_, err := New(lgr.NoOp(), nil, windows, template.EventCtxFuncWithTimeout(context.Background(), time.Second))
+		assert.ErrorIs(t, err, ErrWindows, "Не отклонены окна %v", windows)
+	}
+}
+
+func TestRuleBatchesQuotesUpdates(t *testing.T) {
+	date := time.Date(2022, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	liquidity := newFakeRepo(domain.NewTable(ID, date.AddDate(0, 0, -1), []Liquidity{
+		{Ticker: "AKRN"},
+		{Ticker: "MSST"},
+	}))
+	rule := Rule{
+		logger: lgr.NoOp(),
+		repo:   liquidity,
+		quotes: newFakeRepo(
+			domain.NewTable(quotes.ID("AKRN"), date, []gomoex.Candle{{Begin: date, Value: 2}}),
+			domain.NewTable(quotes.ID("GAZP"), date, []gomoex.Candle{{Begin: date, Value: 3}}),
+		),
+		securities: newFakeRepo(domain.NewTable(securities.ID, date, []gomoex.Security{
+			{Ticker: "AKRN"},
+			{Ticker: "GAZP"},
+		})),
+		windows: []int{1},
+		delay:   10 * time.Millisecond,
+		ctxFunc: template.EventCtxFuncWithTimeout(context.Background(), time.Second),
+	}
+
+	in := make(chan domain.Event)
+	out := make(chan domain.Event, 10)
+	acked := make(chan domain.Event, 10)
+
+	go rule.ActivateWithAck(in, out, func(event domain.Event) { acked <- event })
+
+	events := []domain.Event{
+		domain.NewUpdateCompleted(quotes.ID("AKRN"), date),
+		domain.NewUpdateCompleted(quotes.ID("GAZP"), date),
+		domain.NewUpdateCompleted(quotes.ID("AKRN"), date),
+	}
+	for _, event := range events {
+		in <- event
+	}
+
+	for range events {
+		<-acked
+	}
+
+	assert.IsType(t, domain.UpdateStarted{}, <-out, "Нет события о начале обновления ликвидности")
+	assert.IsType(t, domain.UpdateCompleted{}, <-out, "Нет события об обновлении ликвидности")
+	assert.Equal(t, 1, liquidity.replaced, "Обновления котировок не объединены")
+
+	table, _ := liquidity.Get(context.Background(), ID)
+	assert.Equal(
+		t,
+		[]Liquidity{
+			{Ticker: "AKRN", Date: date, Turnover: []Turnover{{Days: 1, Median: 2}}},
+			{Ticker: "GAZP", Date: date, Turnover: []Turnover{{Days: 1, Median: 3}}},
+		},
+		table.Rows(),
+		"Некорректная таблица ликвидности",
+	)
+
+	close(in)
+}
@@ -0,0 +1,95 @@
+package listing
+
+import (
+	"github.com/WLM1ke/gomoex"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Виды изменений листинга.
+const (
+	KindListed     = "listed"
+	KindDelisted   = "delisted"
+	KindRenamed    = "renamed"
+	KindISIN       = "isin"
+	KindLotSize    = "lot_size"
+	KindBoard      = "board"
+	KindType       = "type"
+	KindInstrument = "instrument"
+)
+
+// diff находит изменения между старым и новым списком бумаг.
+//
+// Бумага, пропавшая из списка, считается переименованной, если ее ISIN есть у одной из новых бумаг.
+func diff(old, fresh []gomoex.Security, date time.Time) (changes []Change) {
+	oldByTicker := byTicker(old)
+	freshByTicker := byTicker(fresh)
+
+	freshOnlyByISIN := make(map[string]gomoex.Security)
+
+	for _, sec := range fresh {
+		if _, ok := oldByTicker[sec.Ticker]; !ok {
+			freshOnlyByISIN[sec.ISIN] = sec
+		}
+	}
+
+	for _, prev := range old {
+		next, ok := freshByTicker[prev.Ticker]
+		if ok {
+			changes = append(changes, fieldChanges(prev, next, date)...)
+
+			continue
+		}
+
+		renamed, ok := freshOnlyByISIN[prev.ISIN]
+		if ok && prev.ISIN != "" {
+			delete(freshOnlyByISIN, prev.ISIN)
+
+			changes = append(changes, Change{date, prev.Ticker, KindRenamed, prev.Ticker, renamed.Ticker})
+			changes = append(changes, fieldChanges(prev, renamed, date)...)
+
+			continue
+		}
+
+		changes = append(changes, Change{date, prev.Ticker, KindDelisted, prev.Ticker, ""})
+	}
+
+	for _, sec := range freshOnlyByISIN {
+		changes = append(changes, Change{date, sec.Ticker, KindListed, "", sec.Ticker})
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Ticker < changes[j].Ticker })
+
+	return changes
+}
+
+func byTicker(rows []gomoex.Security) map[string]gomoex.Security {
+	secs := make(map[string]gomoex.Security, len(rows))
+	for _, sec := range rows {
+		secs[sec.Ticker] = sec
+	}
+
+	return secs
+}
+
+func fieldChanges(prev, next gomoex.Security, date time.Time) (changes []Change) {
+	fields := []struct {
+		kind       string
+		prev, next string
+	}{
+		{KindISIN, prev.ISIN, next.ISIN},
+		{KindLotSize, strconv.Itoa(prev.LotSize), strconv.Itoa(next.LotSize)},
+		{KindBoard, prev.Board, next.Board},
+		{KindType, prev.Type, next.Type},
+		{KindInstrument, prev.Instrument, next.Instrument},
+	}
+
+	for _, field := range fields {
+		if field.prev != field.next {
+			changes = append(changes, Change{date, prev.Ticker, field.kind, field.prev, field.next})
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,39 @@
+package listing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WLM1ke/gomoex"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	date := time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	old := []gomoex.Security{
+		{Ticker: "AKRN", LotSize: 1, ISIN: "RU1", Board: gomoex.BoardTQBR},
+		{Ticker: "MOEX", LotSize: 10, ISIN: "RU2", Board: gomoex.BoardTQBR},
+		{Ticker: "MSST", LotSize: 1, ISIN: "RU3", Board: gomoex.BoardTQBR},
+		{Ticker: "YNDX", LotSize: 1, ISIN: "NL4", Board: gomoex.BoardTQBR},
+	}
+	fresh := []gomoex.Security{
+		{Ticker: "AKRN", LotSize: 1, ISIN: "RU1", Board: gomoex.BoardTQBR},
+		{Ticker: "FIXP", LotSize: 1, ISIN: "US5", Board: gomoex.BoardTQBR},
+		{Ticker: "MOEX", LotSize: 1, ISIN: "RU2", Board: gomoex.BoardTQBR},
+		{Ticker: "YDEX", LotSize: 1, ISIN: "NL4", Board: gomoex.BoardFQBR},
+	}
+
+	assert.Equal(
+		t,
+		[]Change{
+			{date, "FIXP", KindListed, "", "FIXP"},
+			{date, "MOEX", KindLotSize, "10", "1"},
+			{date, "MSST", KindDelisted, "MSST", ""},
+			{date, "YNDX", KindRenamed, "YNDX", "YDEX"},
+			{date, "YNDX", KindBoard, gomoex.BoardTQBR, gomoex.BoardFQBR},
+		},
+		diff(old, fresh, date),
+		"Некорректные изменения листинга",
+	)
+}
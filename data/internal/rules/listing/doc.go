@@ -0,0 +1,2 @@
+// Package listing содержит правило отслеживания изменений в списке торгуемых бумаг.
+package listing
@@ -0,0 +1,134 @@
+package listing
+
+import (
+	"github.com/WLM1ke/gomoex"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/securities"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
+	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+const _group = "listing_changes"
+
+var (
+	// ID - таблица с историей изменений листинга.
+	ID = domain.NewID(_group, _group)
+	// SnapshotID - копия таблицы securities, с которой производилось последнее сравнение.
+	SnapshotID = domain.NewID(_group, "snapshot")
+)
+
+// Change - изменение параметров листинга бумаги.
+type Change struct {
+	Date     time.Time
+	Ticker   string
+	Kind     string
+	OldValue string
+	NewValue string
+}
+
+// Rule - правило отслеживания изменений листинга.
+//
+// После каждого обновления таблицы securities сравнивает ее с сохраненной копией, дописывает изменения в таблицу
+// истории и публикует по событию на каждое изменение. Обновление копии публикуется всегда, чтобы зависящие от
+// истории изменений правила запускались после ее записи.
+type Rule struct {
+	logger  *lgr.Logger
+	secs    repo.ReadWrite[gomoex.Security]
+	changes repo.ReadWrite[Change]
+	ctxFunc template.EventCtxFunc
+}
+
+func New(logger *lgr.Logger, db *mongo.Database, ctxFunc template.EventCtxFunc) *Rule {
+	return &Rule{
+		logger:  logger,
+		secs:    repo.NewMongo[gomoex.Security](db),
+		changes: repo.NewMongo[Change](db),
+		ctxFunc: ctxFunc,
+	}
+}
+
+func (r *Rule) Subscriptions() []domain.Subscription {
+	return []domain.Subscription{domain.Subscribe[domain.UpdateCompleted](securities.ID)}
+}
+
+// Name - название правила.
+func (r *Rule) Name() string {
+	return "ListingRule"
+}
+
+// Produces - таблицы, которые обновляет правило.
+func (r *Rule) Produces() []domain.ID {
+	return []domain.ID{ID, SnapshotID}
+}
+
+func (r *Rule) Activate(in <-chan domain.Event, out chan<- domain.Event) {
+	r.logger.Infof("ListingRule: started")
+	defer r.logger.Infof("ListingRule: stopped")
+
+	for event := range in {
+		event, ok := event.(domain.UpdateCompleted)
+		if !ok || event.ID() != securities.ID {
+			continue
+		}
+
+		for _, newEvent := range r.process(event) {
+			out <- newEvent
+		}
+	}
+}
+
+func (r *Rule) process(event domain.UpdateCompleted) []domain.Event {
+	ctx, cancel := r.ctxFunc()
+	defer cancel()
+
+	fresh, err := r.secs.Get(ctx, securities.ID)
+	if err != nil {
+		return []domain.Event{domain.NewErrorOccurred(event, err)}
+	}
+
+	snapshot, err := r.secs.Get(ctx, SnapshotID)
+	if err != nil {
+		return []domain.Event{domain.NewErrorOccurred(event, err)}
+	}
+
+	update := domain.CausedBy(event, domain.NewUpdateCompleted(ID, event.Date()))
+	snapshotUpdate := domain.CausedBy(event, domain.NewUpdateCompleted(SnapshotID, event.Date()))
+
+	var changes []Change
+	if !snapshot.IsEmpty() {
+		changes = diff(snapshot.Rows(), fresh.Rows(), event.Date())
+	}
+
+	events := make([]domain.Event, 0, len(changes)+4)
+
+	if len(changes) != 0 {
+		events = append(events, domain.NewUpdateStarted(update, r.Name()))
+
+		if err = r.changes.Append(ctx, domain.NewTable(ID, event.Date(), changes).WithSource(r.Name())); err != nil {
+			return append(events, domain.NewErrorOccurred(update, err))
+		}
+	}
+
+	events = append(events, domain.NewUpdateStarted(snapshotUpdate, r.Name()))
+
+	err = r.secs.Replace(ctx, domain.NewTable(SnapshotID, event.Date(), fresh.Rows()).WithSource(r.Name()))
+	if err != nil {
+		return append(events, domain.NewErrorOccurred(snapshotUpdate, err))
+	}
+
+	if len(changes) == 0 {
+		return append(events, snapshotUpdate)
+	}
+
+	for _, change := range changes {
+		events = append(
+			events,
+			domain.NewListingChanged(update, change.Ticker, change.Kind, change.OldValue, change.NewValue),
+		)
+	}
+
+	return append(events, update, snapshotUpdate)
+}
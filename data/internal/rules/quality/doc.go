@@ -0,0 +1,2 @@
+// Package quality содержит правило проверки качества временных рядов котировок.
+package quality
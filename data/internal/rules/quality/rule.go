@@ -0,0 +1,274 @@
+package quality
+
+import (
+	"errors"
+	"fmt"
+	"github.com/WLM1ke/gomoex"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/indexes"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/quotes"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/usd"
+	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"go.mongodb.org/mongo-driver/mongo"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	_group = "quality"
+	// _maxReported - максимальное количество аномалий, перечисляемых в одном событии-ошибке.
+	_maxReported = 5
+)
+
+// _batchDelay - пауза в обновлениях рядов, после которой накопленные обновления проверяются и записываются в таблицу.
+const _batchDelay = 10 * time.Second
+
+var (
+	// ID - таблица с найденными аномалиями всех рядов.
+	ID = domain.NewID(_group, _group)
+	// CheckedID - таблица с последними проверенными датами рядов.
+	CheckedID = domain.NewID(_group, "checked")
+)
+
+// ErrNewAnomalies - в ряде обнаружены новые пропуски торговых дней или выбросы.
+var ErrNewAnomalies = errors.New("new time series anomalies")
+
+// Checked - последняя дата ряда на момент предыдущей проверки.
+type Checked struct {
+	Group string
+	Name  string
+	Date  time.Time
+}
+
+// Rule - правило проверки временных рядов котировок после их обновления.
+//
+// Каждый ряд проверяется по торговому календарю своего рынка. Все аномалии хранятся в одной таблице, поэтому
+// обновления рядов накапливаются и проверяются вместе, когда их поток затихает. Сообщается только об аномалиях после
+// последней проверенной даты ряда, поэтому первая проверка ряда сохраняет найденные в истории аномалии без сообщений.
+type Rule struct {
+	logger  *lgr.Logger
+	repo    repo.ReadWrite[Anomaly]
+	checked repo.ReadWrite[Checked]
+	indexes repo.Read[gomoex.Quote]
+	candles repo.Read[gomoex.Candle]
+	// stock - календарь фондового рынка
+	stock calendar
+	// imoex - календарь фондового рынка, построенный без учета котировок проверяемого по нему индекса МосБиржи
+	imoex calendar
+	// currency - календарь валютного рынка
+	currency calendar
+	sigma    float64
+	delay    time.Duration
+	ctxFunc  template.EventCtxFunc
+}
+
+// New создает правило проверки рядов, считающее выбросами дневные изменения больше sigma стандартных отклонений.
+func New(
+	logger *lgr.Logger,
+	db *mongo.Database,
+	sigma float64,
+	stock *indexes.Calendar,
+	currency *indexes.Calendar,
+	ctxFunc template.EventCtxFunc,
+) *Rule {
+	return &Rule{
+		logger:   logger,
+		repo:     repo.NewMongo[Anomaly](db),
+		checked:  repo.NewMongo[Checked](db),
+		indexes:  repo.NewMongo[gomoex.Quote](db),
+		candles:  repo.NewMongo[gomoex.Candle](db),
+		stock:    stock,
+		imoex:    stock.WithoutReference(),
+		currency: currency,
+		sigma:    sigma,
+		delay:    _batchDelay,
+		ctxFunc:  ctxFunc,
+	}
+}
+
+func (r *Rule) Subscriptions() []domain.Subscription {
+	return []domain.Subscription{
+		domain.Subscribe[domain.UpdateCompleted](usd.ID),
+		domain.SubscribeGroup[domain.UpdateCompleted](indexes.Group),
+		domain.SubscribeGroup[domain.UpdateCompleted](quotes.Group),
+	}
+}
+
+// Name - название правила.
+func (r *Rule) Name() string {
+	return "QualityRule"
+}
+
+// Produces - таблицы, которые обновляет правило.
+func (r *Rule) Produces() []domain.ID {
+	return []domain.ID{ID, CheckedID}
+}
+
+func (r *Rule) Activate(in <-chan domain.Event, out chan<- domain.Event) {
+	r.ActivateWithAck(in, out, func(domain.Event) {})
+}
+
+// ActivateWithAck подтверждает обработку обновлений рядов после записи результатов их проверки.
+func (r *Rule) ActivateWithAck(in <-chan domain.Event, out chan<- domain.Event, ack func(domain.Event)) {
+	r.logger.Infof("QualityRule: started")
+	defer r.logger.Infof("QualityRule: stopped")
+
+	template.ActivateBatched(in, out, ack, r.delay, checkable, r.process)
+}
+
+func checkable(update domain.UpdateCompleted) bool {
+	return update.ID() == usd.ID || update.Group() == indexes.Group || update.Group() == quotes.Group
+}
+
+func (r *Rule) process(batch []domain.UpdateCompleted) (events []domain.Event) {
+	ctx, cancel := r.ctxFunc()
+	defer cancel()
+
+	last := batch[len(batch)-1]
+
+	table, err := r.repo.Get(ctx, ID)
+	if err != nil {
+		return []domain.Event{domain.NewErrorOccurred(last, err)}
+	}
+
+	checkedTable, err := r.checked.Get(ctx, CheckedID)
+	if err != nil {
+		return []domain.Event{domain.NewErrorOccurred(last, err)}
+	}
+
+	rows := table.Rows()
+	checked := checkedByID(checkedTable.Rows())
+	date := table.Date()
+
+	for _, event := range template.Latest(batch) {
+		if event.Date().After(date) {
+			date = event.Date()
+		}
+
+		series, err := r.series(ctx, event.ID())
+		if err != nil {
+			events = append(events, domain.NewErrorOccurred(event, err))
+
+			continue
+		}
+
+		if len(series) == 0 {
+			continue
+		}
+
+		calendar, err := r.calendar(event.ID()).Days(ctx, series[0].Date, series[len(series)-1].Date)
+		if err != nil {
+			events = append(events, domain.NewErrorOccurred(event, err))
+
+			continue
+		}
+
+		since, ok := checked[event.ID()]
+		if !ok {
+			since = series[len(series)-1].Date
+		}
+
+		found := append(missingDays(event.ID(), series, calendar), outliers(event.ID(), series, r.sigma)...)
+
+		var fresh []Anomaly
+
+		rows, fresh = merge(rows, event.ID(), found, since)
+		checked[event.ID()] = series[len(series)-1].Date
+
+		if len(fresh) != 0 {
+			events = append(events, domain.NewErrorOccurred(event, newAnomaliesErr(fresh)))
+		}
+	}
+
+	update := domain.CausedBy(last, domain.NewUpdateCompleted(ID, date))
+	events = append(events, domain.NewUpdateStarted(update, r.Name()))
+
+	if err = r.repo.Replace(ctx, domain.NewTable(ID, date, rows).WithSource(r.Name())); err != nil {
+		return append(events, domain.NewErrorOccurred(update, err))
+	}
+
+	checkedUpdate := domain.CausedBy(last, domain.NewUpdateCompleted(CheckedID, date))
+	events = append(events, domain.NewUpdateStarted(checkedUpdate, r.Name()))
+
+	err = r.checked.Replace(ctx, domain.NewTable(CheckedID, date, checkedRows(checked)).WithSource(r.Name()))
+	if err != nil {
+		return append(events, update, domain.NewErrorOccurred(checkedUpdate, err))
+	}
+
+	return append(events, update, checkedUpdate)
+}
+
+func checkedByID(rows []Checked) map[domain.ID]time.Time {
+	checked := make(map[domain.ID]time.Time, len(rows))
+	for _, row := range rows {
+		checked[domain.NewID(row.Group, row.Name)] = row.Date
+	}
+
+	return checked
+}
+
+func checkedRows(checked map[domain.ID]time.Time) []Checked {
+	rows := make([]Checked, 0, len(checked))
+	for id, date := range checked {
+		rows = append(rows, Checked{Group: string(id.Group()), Name: string(id.Name()), Date: date})
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Group != rows[j].Group {
+			return rows[i].Group < rows[j].Group
+		}
+
+		return rows[i].Name < rows[j].Name
+	})
+
+	return rows
+}
+
+func newAnomaliesErr(fresh []Anomaly) error {
+	descriptions := make([]string, 0, _maxReported)
+
+	for n, anomaly := range fresh {
+		if n == _maxReported {
+			descriptions = append(descriptions, fmt.Sprintf("and %d more", len(fresh)-n))
+
+			break
+		}
+
+		descriptions = append(descriptions, anomaly.String())
+	}
+
+	return fmt.Errorf("%w: %s", ErrNewAnomalies, strings.Join(descriptions, ", "))
+}
+
+// merge заменяет аномалии заданного ряда на найденные и возвращает ранее не известные аномалии после даты since.
+//
+// Неизвестные аномалии не позже since обнаружены в уже проверенной части ряда, например, при первой проверке или
+// из-за изменения статистик ряда, поэтому сохраняются без сообщения о них.
+func merge(rows []Anomaly, id domain.ID, found []Anomaly, since time.Time) (merged, fresh []Anomaly) {
+	known := make(map[string]bool)
+
+	for _, row := range rows {
+		if row.Group == string(id.Group()) && row.Name == string(id.Name()) {
+			known[row.key()] = true
+
+			continue
+		}
+
+		merged = append(merged, row)
+	}
+
+	for _, anomaly := range found {
+		if !known[anomaly.key()] && anomaly.Date.After(since) {
+			fresh = append(fresh, anomaly)
+		}
+	}
+
+	merged = append(merged, found...)
+
+	sort.Slice(merged, func(i, j int) bool { return merged[i].less(merged[j]) })
+
+	return merged, fresh
+}
@@ -0,0 +1,277 @@
+package quality
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/WLM1ke/gomoex"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/indexes"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/quotes"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/usd"
+	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"github.com/stretchr/testify/assert"
+)
+
+var _testID = quotes.ID("AKRN")
+
+func day(n int) time.Time {
+	return time.Date(2022, 1, n, 0, 0, 0, 0, time.UTC)
+}
+
+func days(ns ...int) []time.Time {
+	out := make([]time.Time, 0, len(ns))
+	for _, n := range ns {
+		out = append(out, day(n))
+	}
+
+	return out
+}
+
+func anomaly(n int, kind string) Anomaly {
+	return Anomaly{Group: string(_testID.Group()), Name: string(_testID.Name()), Date: day(n), Kind: kind}
+}
+
+func TestMissingDays(t *testing.T) {
+	series := []point{{Date: day(3)}, {Date: day(5)}, {Date: day(7)}}
+
+	testCases := []struct {
+		name     string
+		series   []point
+		calendar []time.Time
+		missing  []Anomaly
+	}{
+		{"пустой ряд", nil, days(3, 4, 5), nil},
+		{"нет календаря", series, nil, nil},
+		{"без пропусков", series, days(3, 5, 7), nil},
+		{"дни вне ряда", series, days(1, 2, 3, 5, 7, 8), nil},
+		{"пропуски", series, days(3, 4, 5, 6, 7), []Anomaly{anomaly(4, KindMissing), anomaly(6, KindMissing)}},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(
+			t,
+			testCase.missing,
+			missingDays(_testID, testCase.series, testCase.calendar),
+			testCase.name,
+		)
+	}
+}
+
+func TestOutliers(t *testing.T) {
+	steady := make([]point, 0, 20)
+	for n := 1; n <= 20; n++ {
+		steady = append(steady, point{Date: day(n), Close: 100 + float64(n%2)})
+	}
+
+	jump := append(append([]point(nil), steady...), point{Date: day(21), Close: 1000})
+
+	testCases := []struct {
+		name     string
+		series   []point
+		sigma    float64
+		outliers []time.Time
+	}{
+		{"короткий ряд", steady[:2], 3, nil},
+		{"постоянный ряд", []point{{day(1), 1}, {day(2), 1}, {day(3), 1}}, 3, nil},
+		{"без выбросов", steady, 3, nil},
+		{"выброс", jump, 3, days(21)},
+		{"выброс ниже порога", jump, 100, nil},
+		{"нулевые цены пропускаются", append([]point{{day(0), 0}}, steady...), 3, nil},
+	}
+
+	for _, testCase := range testCases {
+		var got []time.Time
+		for _, anomaly := range outliers(_testID, testCase.series, testCase.sigma) {
+			assert.Equal(t, KindOutlier, anomaly.Kind, testCase.name)
+			got = append(got, anomaly.Date)
+		}
+
+		assert.Equal(t, testCase.outliers, got, testCase.name)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	other := Anomaly{Group: "indexes", Name: "IMOEX", Date: day(2), Kind: KindMissing}
+
+	testCases := []struct {
+		name   string
+		rows   []Anomaly
+		found  []Anomaly
+		since  time.Time
+		merged []Anomaly
+		fresh  []Anomaly
+	}{
+		{
+			"новые после проверки",
+			[]Anomaly{other, anomaly(2, KindMissing)},
+			[]Anomaly{anomaly(2, KindMissing), anomaly(5, KindOutlier)},
+			day(3),
+			[]Anomaly{other, anomaly(2, KindMissing), anomaly(5, KindOutlier)},
+			[]Anomaly{anomaly(5, KindOutlier)},
+		},
+		{
+			"новые до проверки не сообщаются",
+			[]Anomaly{other},
+			[]Anomaly{anomaly(1, KindOutlier), anomaly(3, KindMissing)},
+			day(3),
+			[]Anomaly{other, anomaly(1, KindOutlier), anomaly(3, KindMissing)},
+			nil,
+		},
+		{
+			"исчезнувшие удаляются",
+			[]Anomaly{anomaly(1, KindOutlier), other},
+			nil,
+			day(3),
+			[]Anomaly{other},
+			nil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		merged, fresh := merge(testCase.rows, _testID, testCase.found, testCase.since)
+
+		assert.Equal(t, testCase.merged, merged, testCase.name)
+		assert.Equal(t, testCase.fresh, fresh, testCase.name)
+	}
+}
+
+type fakeRepo[R any] struct {
+	lock   sync.Mutex
+	tables map[domain.ID]domain.Table[R]
+}
+
+func newFakeRepo[R any](tables ...domain.Table[R]) *fakeRepo[R] {
+	repo := fakeRepo[R]{tables: make(map[domain.ID]domain.Table[R])}
+	for _, table := range tables {
+		repo.tables[table.ID()] = table
+	}
+
+	return &repo
+}
+
+func (f *fakeRepo[R]) Get(_ context.Context, id domain.ID) (domain.Table[R], error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if table, ok := f.tables[id]; ok {
+		return table, nil
+	}
+
+	return domain.NewEmptyTable[R](id), nil
+}
+
+func (f *fakeRepo[R]) Replace(_ context.Context, table domain.Table[R]) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.tables[table.ID()] = table
+
+	return nil
+}
+
+func (f *fakeRepo[R]) Append(_ context.Context, _ domain.Table[R]) error {
+	panic("quality tables are replaced only")
+}
+
+func candles(ns ...int) []gomoex.Candle {
+	rows := make([]gomoex.Candle, 0, len(ns))
+	for _, n := range ns {
+		rows = append(rows, gomoex.Candle{Begin: day(n), Close: 1})
+	}
+
+	return rows
+}
+
+// fakeCalendar - торговый календарь с заданными торговыми днями.
+type fakeCalendar []time.Time
+
+func (f fakeCalendar) Days(_ context.Context, from, till time.Time) (days []time.Time, err error) {
+	for _, date := range f {
+		if !date.Before(from) && !date.After(till) {
+			days = append(days, date)
+		}
+	}
+
+	return days, nil
+}
+
+func TestCalendarByMarket(t *testing.T) {
+	stock, currency, imoex := fakeCalendar(days(1)), fakeCalendar(days(2)), fakeCalendar(days(3))
+	rule := Rule{stock: stock, currency: currency, imoex: imoex}
+
+	assert.Equal(t, stock, rule.calendar(_testID), "Акции проверяются не по календарю фондового рынка")
+	assert.Equal(t, stock, rule.calendar(domain.NewID(string(indexes.Group), "RVI")), "Индекс не по календарю рынка")
+	assert.Equal(t, imoex, rule.calendar(indexes.IMOEX), "Индекс МосБиржи проверяется по своим же датам")
+	assert.Equal(t, currency, rule.calendar(usd.ID), "Курс доллара проверяется не по календарю валютного рынка")
+}
+
+func TestProcessReportsOnlyAfterLastCheck(t *testing.T) {
+	imoex := make([]gomoex.Quote, 0, 10)
+	for n := 1; n <= 10; n++ {
+		imoex = append(imoex, gomoex.Quote{Date: day(n)})
+	}
+
+	candleRepo := newFakeRepo(
+		domain.NewTable(_testID, day(5), candles(1, 3, 5)),
+		domain.NewTable(usd.ID, day(5), candles(1, 5)),
+	)
+	anomalies := newFakeRepo[Anomaly]()
+	checked := newFakeRepo[Checked]()
+
+	rule := Rule{
+		logger:   lgr.NoOp(),
+		repo:     anomalies,
+		checked:  checked,
+		indexes:  newFakeRepo(domain.NewTable(indexes.IMOEX, day(10), imoex)),
+		candles:  candleRepo,
+		stock:    fakeCalendar(days(1, 2, 3, 4, 5, 6, 7, 8, 9, 10)),
+		currency: fakeCalendar(days(1, 5)),
+		sigma:    3,
+		ctxFunc:  template.EventCtxFuncWithTimeout(context.Background(), time.Second),
+	}
+
+	batch := []domain.UpdateCompleted{
+		domain.NewUpdateCompleted(_testID, day(5)),
+		domain.NewUpdateCompleted(usd.ID, day(5)),
+	}
+
+	for _, event := range rule.process(batch) {
+		_, failed := event.(domain.ErrorOccurred)
+		assert.False(t, failed, "Сообщение об аномалиях при первой проверке: %s", event)
+	}
+
+	table, _ := anomalies.Get(context.Background(), ID)
+	assert.Equal(
+		t,
+		[]Anomaly{anomaly(2, KindMissing), anomaly(4, KindMissing)},
+		table.Rows(),
+		"Аномалии истории не сохранены или найдены пропуски по чужому календарю",
+	)
+
+	candleRepo.tables[_testID] = domain.NewTable(_testID, day(8), candles(1, 3, 5, 6, 8))
+
+	var reported []domain.Event
+
+	for _, event := range rule.process([]domain.UpdateCompleted{domain.NewUpdateCompleted(_testID, day(8))}) {
+		if _, failed := event.(domain.ErrorOccurred); failed {
+			reported = append(reported, event)
+		}
+	}
+
+	assert.Len(t, reported, 1, "Нет сообщения о новых аномалиях")
+	assert.ErrorIs(t, reported[0].(domain.ErrorOccurred).Err(), ErrNewAnomalies)
+	assert.Contains(t, reported[0].String(), "quotes/AKRN 2022-01-07 missing")
+	assert.NotContains(t, reported[0].String(), "2022-01-02", "Повторное сообщение о проверенной аномалии")
+
+	checkedTable, _ := checked.Get(context.Background(), CheckedID)
+	assert.Equal(
+		t,
+		[]Checked{{Group: "quotes", Name: "AKRN", Date: day(8)}, {Group: "usd", Name: "usd", Date: day(5)}},
+		checkedTable.Rows(),
+		"Некорректные даты проверки рядов",
+	)
+}
@@ -0,0 +1,189 @@
+package quality
+
+import (
+	"context"
+	"fmt"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/indexes"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/usd"
+	"math"
+	"time"
+)
+
+// Виды аномалий.
+const (
+	KindMissing = "missing"
+	KindOutlier = "outlier"
+)
+
+// Anomaly - пропуск торгового дня или выброс в таблице с котировками.
+type Anomaly struct {
+	Group string
+	Name  string
+	Date  time.Time
+	Kind  string
+	Value float64
+}
+
+func (a Anomaly) String() string {
+	return fmt.Sprintf("%s/%s %s %s", a.Group, a.Name, a.Date.Format(`2006-01-02`), a.Kind)
+}
+
+// key - ключ аномалии без учета значения, которое может уточняться при пересчете статистик ряда.
+func (a Anomaly) key() string {
+	return fmt.Sprintf("%s/%s/%d/%s", a.Group, a.Name, a.Date.Unix(), a.Kind)
+}
+
+func (a Anomaly) less(other Anomaly) bool {
+	switch {
+	case a.Group != other.Group:
+		return a.Group < other.Group
+	case a.Name != other.Name:
+		return a.Name < other.Name
+	case !a.Date.Equal(other.Date):
+		return a.Date.Before(other.Date)
+	default:
+		return a.Kind < other.Kind
+	}
+}
+
+// point - значение ряда на дату.
+type point struct {
+	Date  time.Time
+	Close float64
+}
+
+func (r *Rule) series(ctx context.Context, id domain.ID) ([]point, error) {
+	if id.Group() == indexes.Group {
+		table, err := r.indexes.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		series := make([]point, 0, len(table.Rows()))
+		for _, row := range table.Rows() {
+			series = append(series, point{Date: row.Date, Close: row.Close})
+		}
+
+		return series, nil
+	}
+
+	table, err := r.candles.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	series := make([]point, 0, len(table.Rows()))
+	for _, row := range table.Rows() {
+		series = append(series, point{Date: domain.Day(row.Begin), Close: row.Close})
+	}
+
+	return series, nil
+}
+
+// calendar - торговый календарь рынка.
+type calendar interface {
+	// Days - торговые дни с from по till включительно.
+	Days(ctx context.Context, from, till time.Time) ([]time.Time, error)
+}
+
+// calendar - торговый календарь, по которому проверяется ряд.
+//
+// Акции и индексы торгуются и рассчитываются в торговые дни фондового рынка, а биржевой курс доллара - в торговые
+// дни валютного рынка. Календарь фондового рынка строится по датам котировок индекса МосБиржи, поэтому сам индекс
+// проверяется по календарю, построенному только по настройкам праздников и рабочих выходных.
+func (r *Rule) calendar(id domain.ID) calendar {
+	switch id {
+	case usd.ID:
+		return r.currency
+	case indexes.IMOEX:
+		return r.imoex
+	default:
+		return r.stock
+	}
+}
+
+// missingDays находит торговые дни внутри ряда, для которых нет значений.
+func missingDays(id domain.ID, series []point, calendar []time.Time) (anomalies []Anomaly) {
+	if len(series) == 0 {
+		return nil
+	}
+
+	first, last := series[0].Date, series[len(series)-1].Date
+	n := 0
+
+	for _, day := range calendar {
+		if day.Before(first) || day.After(last) {
+			continue
+		}
+
+		for n < len(series) && series[n].Date.Before(day) {
+			n++
+		}
+
+		if n == len(series) || !series[n].Date.Equal(day) {
+			anomalies = append(anomalies, Anomaly{
+				Group: string(id.Group()),
+				Name:  string(id.Name()),
+				Date:  day,
+				Kind:  KindMissing,
+			})
+		}
+	}
+
+	return anomalies
+}
+
+// outliers находит дни, изменение логарифма цены в которые отклоняется от среднего больше, чем на sigma
+// стандартных отклонений.
+func outliers(id domain.ID, series []point, sigma float64) (anomalies []Anomaly) {
+	returns := make([]float64, 0, len(series))
+	days := make([]time.Time, 0, len(series))
+
+	for n := 1; n < len(series); n++ {
+		ret := math.Log(series[n].Close / series[n-1].Close)
+		if math.IsNaN(ret) || math.IsInf(ret, 0) {
+			continue
+		}
+
+		returns = append(returns, ret)
+		days = append(days, series[n].Date)
+	}
+
+	mean, std := meanStd(returns)
+	if std == 0 {
+		return nil
+	}
+
+	for n, ret := range returns {
+		if math.Abs(ret-mean) > sigma*std {
+			anomalies = append(anomalies, Anomaly{
+				Group: string(id.Group()),
+				Name:  string(id.Name()),
+				Date:  days[n],
+				Kind:  KindOutlier,
+				Value: ret,
+			})
+		}
+	}
+
+	return anomalies
+}
+
+func meanStd(values []float64) (mean, std float64) {
+	if len(values) < 2 {
+		return 0, 0
+	}
+
+	for _, value := range values {
+		mean += value
+	}
+
+	mean /= float64(len(values))
+
+	for _, value := range values {
+		std += (value - mean) * (value - mean)
+	}
+
+	return mean, math.Sqrt(std / float64(len(values)-1))
+}
@@ -0,0 +1,2 @@
+// Package quotes содержит правило обновления котировок отдельных бумаг.
+package quotes
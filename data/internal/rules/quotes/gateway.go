@@ -0,0 +1,195 @@
+package quotes
+
+import (
+	"context"
+	"fmt"
+	"github.com/WLM1ke/gomoex"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/listing"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/securities"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
+	"sort"
+	"time"
+)
+
+const (
+	_format = `2006-01-02`
+	_engine = gomoex.EngineStock
+)
+
+// boardsMarkets - рынки, на которых находятся основные режимы торгов бумаг из таблицы securities.
+var boardsMarkets = map[string]string{
+	gomoex.BoardTQBR: gomoex.MarketShares,
+	gomoex.BoardTQTF: gomoex.MarketShares,
+	gomoex.BoardFQBR: gomoex.MarketForeignShares,
+}
+
+type gateway struct {
+	iss        *gomoex.ISSClient
+	securities repo.Read[gomoex.Security]
+	changes    repo.Read[listing.Change]
+}
+
+// Source - источник данных шлюза.
+func (g gateway) Source() string {
+	return template.SourceISS
+}
+
+func (g gateway) Get(ctx context.Context, table domain.Table[gomoex.Candle], date time.Time) ([]gomoex.Candle, error) {
+	market, err := g.market(ctx, string(table.Name()))
+	if err != nil {
+		return nil, err
+	}
+
+	end := date.Format(_format)
+
+	if table.IsEmpty() {
+		return g.history(ctx, string(table.Name()), market, end)
+	}
+
+	rows, err := g.iss.MarketCandles(
+		ctx,
+		_engine,
+		market,
+		string(table.Name()),
+		table.LastRow().Begin.Format(_format),
+		end,
+		gomoex.IntervalDay,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	moved, err := g.boardChanged(ctx, string(table.Name()), table.LastRow().Begin)
+	if err != nil {
+		return nil, err
+	}
+
+	return stitch(rows, table.LastRow(), moved), nil
+}
+
+// segment - рынок, на котором бумага торговалась до указанной даты.
+type segment struct {
+	market string
+	till   time.Time
+}
+
+// history загружает котировки с начала торгов бумагой.
+//
+// Рынок определяет основной режим торгов, поэтому котировки до смены режима торгов загружаются с рынков, на которых
+// бумага торговалась раньше, и дополняются котировками с текущего рынка.
+func (g gateway) history(ctx context.Context, ticker, market, end string) ([]gomoex.Candle, error) {
+	segments, err := g.earlierMarkets(ctx, ticker)
+	if err != nil {
+		return nil, err
+	}
+
+	segments = append(segments, segment{market: market})
+
+	var rows []gomoex.Candle
+
+	for _, seg := range segments {
+		till := end
+		if !seg.till.IsZero() {
+			till = seg.till.Format(_format)
+		}
+
+		candles, err := g.iss.MarketCandles(ctx, _engine, seg.market, ticker, "", till, gomoex.IntervalDay)
+		if err != nil {
+			return nil, err
+		}
+
+		rows = merge(rows, candles)
+	}
+
+	return rows, nil
+}
+
+// earlierMarkets находит по истории изменений листинга рынки, на которых бумага торговалась до смены режима торгов.
+//
+// Котировки в режимах торгов с неизвестными рынками не загружаются.
+func (g gateway) earlierMarkets(ctx context.Context, ticker string) ([]segment, error) {
+	table, err := g.changes.Get(ctx, listing.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []segment
+
+	for _, change := range table.Rows() {
+		if change.Ticker != ticker || change.Kind != listing.KindBoard {
+			continue
+		}
+
+		if market, ok := boardsMarkets[change.OldValue]; ok {
+			segments = append(segments, segment{market: market, till: change.Date})
+		}
+	}
+
+	sort.SliceStable(segments, func(i, j int) bool { return segments[i].till.Before(segments[j].till) })
+
+	return segments, nil
+}
+
+// merge дополняет котировки с предыдущего рынка котировками с более нового, которые считаются приоритетными за
+// совпадающие даты.
+func merge(rows, newer []gomoex.Candle) []gomoex.Candle {
+	if len(newer) == 0 {
+		return rows
+	}
+
+	n := sort.Search(len(rows), func(i int) bool { return !rows[i].Begin.Before(newer[0].Begin) })
+
+	return append(rows[:n:n], newer...)
+}
+
+// boardChanged проверяет, менялся ли режим торгов бумаги не раньше последней сохраненной даты.
+func (g gateway) boardChanged(ctx context.Context, ticker string, since time.Time) (bool, error) {
+	table, err := g.changes.Get(ctx, listing.ID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, change := range table.Rows() {
+		if change.Ticker == ticker && change.Kind == listing.KindBoard && !change.Date.Before(since) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// stitch стыкует новые котировки с сохраненными.
+//
+// При переходе бумаги в другой режим торгов котировки на новом рынке могут отсутствовать на последнюю сохраненную
+// дату, поэтому для стыковки рядов добавляется последняя сохраненная строка. Без смены режима расхождение первой
+// даты не исправляется и должно быть отклонено валидатором.
+func stitch(rows []gomoex.Candle, last gomoex.Candle, moved bool) []gomoex.Candle {
+	if !moved || len(rows) == 0 || rows[0].Begin.Equal(last.Begin) {
+		return rows
+	}
+
+	return append([]gomoex.Candle{last}, rows...)
+}
+
+func (g gateway) market(ctx context.Context, ticker string) (string, error) {
+	table, err := g.securities.Get(ctx, securities.ID)
+	if err != nil {
+		return "", err
+	}
+
+	rows := table.Rows()
+
+	n := sort.Search(len(rows), func(i int) bool { return rows[i].Ticker >= ticker })
+	if n == len(rows) || rows[n].Ticker != ticker {
+		return "", fmt.Errorf("%w: ticker %s not found in securities", template.ErrRuleGateway, ticker)
+	}
+
+	market, ok := boardsMarkets[rows[n].Board]
+	if !ok {
+		return "", fmt.Errorf("%w: unknown board %s for %s", template.ErrRuleGateway, rows[n].Board, ticker)
+	}
+
+	return market, nil
+}
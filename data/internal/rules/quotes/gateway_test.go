@@ -0,0 +1,162 @@
+package quotes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/WLM1ke/gomoex"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/listing"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/securities"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeRead[R any] struct {
+	tables map[domain.ID]domain.Table[R]
+}
+
+func (f fakeRead[R]) Get(_ context.Context, id domain.ID) (domain.Table[R], error) {
+	if table, ok := f.tables[id]; ok {
+		return table, nil
+	}
+
+	return domain.NewEmptyTable[R](id), nil
+}
+
+func day(d int) time.Time {
+	return time.Date(2022, 2, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestStitch(t *testing.T) {
+	last := gomoex.Candle{Begin: day(1), Close: 99}
+
+	testCases := []struct {
+		name  string
+		rows  []gomoex.Candle
+		moved bool
+		out   []gomoex.Candle
+	}{
+		{"нет новых строк", nil, true, nil},
+		{"ряд продолжается", []gomoex.Candle{last, {Begin: day(2)}}, true, []gomoex.Candle{last, {Begin: day(2)}}},
+		{"режим не менялся", []gomoex.Candle{{Begin: day(2)}}, false, []gomoex.Candle{{Begin: day(2)}}},
+		{"смена режима", []gomoex.Candle{{Begin: day(2)}}, true, []gomoex.Candle{last, {Begin: day(2)}}},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.out, stitch(testCase.rows, last, testCase.moved), testCase.name)
+	}
+}
+
+func TestGatewayBoardChanged(t *testing.T) {
+	changes := []listing.Change{
+		{Date: day(1), Ticker: "AKRN", Kind: listing.KindLotSize, OldValue: "1", NewValue: "10"},
+		{Date: day(3), Ticker: "AKRN", Kind: listing.KindBoard, OldValue: gomoex.BoardTQBR, NewValue: gomoex.BoardTQTF},
+		{Date: day(5), Ticker: "MOEX", Kind: listing.KindBoard, OldValue: gomoex.BoardTQBR, NewValue: gomoex.BoardTQTF},
+	}
+	g := gateway{changes: fakeRead[listing.Change]{tables: map[domain.ID]domain.Table[listing.Change]{
+		listing.ID: domain.NewTable(listing.ID, day(5), changes),
+	}}}
+
+	testCases := []struct {
+		ticker string
+		since  time.Time
+		moved  bool
+	}{
+		{"AKRN", day(2), true},
+		{"AKRN", day(3), true},
+		{"AKRN", day(4), false},
+		{"MOEX", day(4), true},
+		{"GAZP", day(1), false},
+	}
+
+	for _, testCase := range testCases {
+		moved, err := g.boardChanged(context.Background(), testCase.ticker, testCase.since)
+		assert.Nil(t, err)
+		assert.Equal(t, testCase.moved, moved, "%s c %s", testCase.ticker, testCase.since)
+	}
+}
+
+func TestGatewayEarlierMarkets(t *testing.T) {
+	changes := []listing.Change{
+		{Date: day(1), Ticker: "AKRN", Kind: listing.KindBoard, OldValue: gomoex.BoardFQBR, NewValue: gomoex.BoardTQTF},
+		{Date: day(2), Ticker: "MOEX", Kind: listing.KindBoard, OldValue: gomoex.BoardTQBR, NewValue: gomoex.BoardTQTF},
+		{Date: day(3), Ticker: "AKRN", Kind: listing.KindLotSize, OldValue: "1", NewValue: "10"},
+		{Date: day(4), Ticker: "AKRN", Kind: listing.KindBoard, OldValue: "SMAL", NewValue: gomoex.BoardTQBR},
+		{Date: day(5), Ticker: "AKRN", Kind: listing.KindBoard, OldValue: gomoex.BoardTQTF, NewValue: gomoex.BoardTQBR},
+	}
+	g := gateway{changes: fakeRead[listing.Change]{tables: map[domain.ID]domain.Table[listing.Change]{
+		listing.ID: domain.NewTable(listing.ID, day(5), changes),
+	}}}
+
+	segments, err := g.earlierMarkets(context.Background(), "AKRN")
+	assert.Nil(t, err)
+	assert.Equal(t, []segment{
+		{market: gomoex.MarketForeignShares, till: day(1)},
+		{market: gomoex.MarketShares, till: day(5)},
+	}, segments)
+
+	segments, err = g.earlierMarkets(context.Background(), "GAZP")
+	assert.Nil(t, err)
+	assert.Empty(t, segments)
+}
+
+func TestMerge(t *testing.T) {
+	old := []gomoex.Candle{{Begin: day(1), Close: 1}, {Begin: day(2), Close: 2}, {Begin: day(3), Close: 3}}
+
+	testCases := []struct {
+		name  string
+		rows  []gomoex.Candle
+		newer []gomoex.Candle
+		out   []gomoex.Candle
+	}{
+		{"первый рынок", nil, old, old},
+		{"нет котировок на новом рынке", old, nil, old},
+		{
+			"пересечение дат",
+			old,
+			[]gomoex.Candle{{Begin: day(3), Close: 30}, {Begin: day(4), Close: 40}},
+			[]gomoex.Candle{old[0], old[1], {Begin: day(3), Close: 30}, {Begin: day(4), Close: 40}},
+		},
+		{
+			"без пересечения",
+			old,
+			[]gomoex.Candle{{Begin: day(5), Close: 50}},
+			[]gomoex.Candle{old[0], old[1], old[2], {Begin: day(5), Close: 50}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.out, merge(testCase.rows, testCase.newer), testCase.name)
+	}
+
+	assert.Equal(t, 3, len(old), "Исходные котировки изменены")
+	assert.Equal(t, 3.0, old[2].Close, "Исходные котировки изменены")
+}
+
+func TestSelectorDedupesTickers(t *testing.T) {
+	secs := []gomoex.Security{
+		{Ticker: "AKRN", Board: gomoex.BoardTQBR},
+		{Ticker: "AKRN", Board: gomoex.BoardTQTF},
+		{Ticker: "MOEX", Board: gomoex.BoardTQBR},
+	}
+	s := selector{securities: fakeRead[gomoex.Security]{tables: map[domain.ID]domain.Table[gomoex.Security]{
+		securities.ID: domain.NewTable(securities.ID, day(1), secs),
+	}}}
+
+	ids, err := s.Select(context.Background(), domain.NewUpdateCompleted(listing.SnapshotID, day(1)))
+	assert.Nil(t, err)
+	assert.Equal(t, []domain.ID{ID("AKRN"), ID("MOEX")}, ids)
+
+	ids, err = s.Select(context.Background(), domain.NewUpdateCompleted(securities.ID, day(1)))
+	assert.Nil(t, err)
+	assert.Empty(t, ids)
+}
+
+func TestValidatorRejectsGapWithoutStitch(t *testing.T) {
+	table := domain.NewTable(ID("AKRN"), day(1), []gomoex.Candle{{Begin: day(1), Close: 99}})
+
+	assert.ErrorIs(t, validator(table, []gomoex.Candle{{Begin: day(2)}}), template.ErrNewRowsValidation)
+	assert.Nil(t, validator(table, stitch([]gomoex.Candle{{Begin: day(2)}}, table.LastRow(), true)))
+}
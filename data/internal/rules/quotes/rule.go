@@ -0,0 +1,37 @@
+package quotes
+
+import (
+	"github.com/WLM1ke/gomoex"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/listing"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
+	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const _group = "quotes"
+
+// Group - группа таблиц с котировками отдельных бумаг.
+const Group domain.Group = _group
+
+// ID - таблица с котировками заданной бумаги.
+func ID(ticker string) domain.ID {
+	return domain.NewID(_group, ticker)
+}
+
+// New создает правило обновления дневных котировок для всех бумаг из таблицы securities.
+func New(logger *lgr.Logger, db *mongo.Database, iss *gomoex.ISSClient, opts template.Options) domain.Rule {
+	securities := repo.NewMongo[gomoex.Security](db)
+
+	return template.NewRule[gomoex.Candle](
+		"QuotesRule",
+		logger,
+		repo.NewMongo[gomoex.Candle](db),
+		selector{securities: securities},
+		gateway{iss: iss, securities: securities, changes: repo.NewMongo[listing.Change](db)},
+		validator,
+		true,
+		opts,
+	)
+}
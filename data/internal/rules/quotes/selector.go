@@ -0,0 +1,50 @@
+package quotes
+
+import (
+	"context"
+	"github.com/WLM1ke/gomoex"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/listing"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/securities"
+)
+
+// selector выбирает котировки для обновления после сравнения листинга, чтобы шлюз видел смены режимов торгов
+// на дату обновления.
+type selector struct {
+	securities repo.Read[gomoex.Security]
+}
+
+func (s selector) Subscriptions() []domain.Subscription {
+	return []domain.Subscription{domain.Subscribe[domain.UpdateCompleted](listing.SnapshotID)}
+}
+
+func (s selector) Produces() []domain.ID {
+	return []domain.ID{domain.GroupID(Group)}
+}
+
+func (s selector) Select(ctx context.Context, event domain.Event) (ids []domain.ID, err error) {
+	switch selected := event.(type) {
+	case domain.UpdateCompleted:
+		if selected.ID() == listing.SnapshotID {
+			table, err := s.securities.Get(ctx, securities.ID)
+			if err != nil {
+				return nil, err
+			}
+
+			seen := make(map[string]bool, len(table.Rows()))
+
+			for _, sec := range table.Rows() {
+				if seen[sec.Ticker] {
+					continue
+				}
+
+				seen[sec.Ticker] = true
+
+				ids = append(ids, ID(sec.Ticker))
+			}
+		}
+	}
+
+	return ids, err
+}
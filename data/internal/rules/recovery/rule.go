@@ -0,0 +1,99 @@
+// Package recovery содержит правило, повторяющее обновления, пропущенные из-за недоступности источника данных.
+package recovery
+
+import (
+	"context"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
+	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// deadLetters - хранилище неудачных обновлений таблиц.
+type deadLetters interface {
+	List(ctx context.Context) ([]repo.DeadLetter, error)
+}
+
+// Rule - правило, запрашивающее повторное обновление таблиц после восстановления источника данных.
+//
+// Пока предохранитель источника разомкнут, обновления из него пропускаются и сохраняются в хранилище неудачных
+// обновлений. После восстановления источника правило запрашивает их повтор у правил, которые их пропустили.
+type Rule struct {
+	logger  *lgr.Logger
+	letters deadLetters
+	ctxFunc template.EventCtxFunc
+}
+
+// New создает правило повтора пропущенных обновлений.
+func New(logger *lgr.Logger, db *mongo.Database, ctxFunc template.EventCtxFunc) *Rule {
+	return &Rule{
+		logger:  logger,
+		letters: repo.NewDeadLetters(db),
+		ctxFunc: ctxFunc,
+	}
+}
+
+// Subscriptions - события о восстановлении источников данных.
+func (r *Rule) Subscriptions() []domain.Subscription {
+	return []domain.Subscription{domain.SubscribeAll[domain.SourceRecovered]()}
+}
+
+// Name - название правила.
+func (r *Rule) Name() string {
+	return "SourceRecoveryRule"
+}
+
+// Produces - правило не обновляет таблицы, а только запрашивает повторные обновления.
+func (r *Rule) Produces() []domain.ID {
+	return nil
+}
+
+func (r *Rule) Activate(in <-chan domain.Event, out chan<- domain.Event) {
+	r.logger.Infof("SourceRecoveryRule: started")
+	defer r.logger.Infof("SourceRecoveryRule: stopped")
+
+	for event := range in {
+		recovered, ok := event.(domain.SourceRecovered)
+		if !ok {
+			continue
+		}
+
+		retries, err := r.retries(recovered)
+		if err != nil {
+			out <- domain.NewErrorOccurred(recovered, err)
+
+			continue
+		}
+
+		r.logger.Infof("SourceRecoveryRule: %d skipped updates requested after %s", len(retries), recovered)
+
+		for _, retry := range retries {
+			out <- retry
+		}
+	}
+}
+
+// retries - запросы на повтор обновлений, пропущенных из-за недоступности восстановленного источника.
+func (r *Rule) retries(recovered domain.SourceRecovered) ([]domain.Event, error) {
+	ctx, cancel := r.ctxFunc()
+	defer cancel()
+
+	letters, err := r.letters.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	retries := make([]domain.Event, 0, len(letters))
+
+	for _, letter := range letters {
+		if !letter.Skipped || letter.Source != string(recovered.Name()) {
+			continue
+		}
+
+		retry := domain.NewRetryRequested(letter.ID(), letter.Date, letter.Rule)
+		retries = append(retries, domain.CausedBy(recovered, retry))
+	}
+
+	return retries, nil
+}
@@ -0,0 +1,59 @@
+package recovery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
+	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeLetters struct {
+	letters []repo.DeadLetter
+}
+
+func (f fakeLetters) List(_ context.Context) ([]repo.DeadLetter, error) {
+	return f.letters, nil
+}
+
+func TestRuleRequestsSkippedUpdates(t *testing.T) {
+	date := time.Date(2022, time.January, 10, 0, 0, 0, 0, time.UTC)
+	letters := fakeLetters{letters: []repo.DeadLetter{
+		{Group: "quotes", Name: "AKRN", Date: date, Rule: "QuotesRule", Source: template.SourceISS, Skipped: true},
+		{Group: "quotes", Name: "GAZP", Date: date, Rule: "QuotesRule", Source: template.SourceISS},
+		{Group: "cpi", Name: "cpi", Date: date, Rule: "CPIRule", Source: "rosstat", Skipped: true},
+	}}
+
+	rule := Rule{
+		logger:  lgr.NoOp(),
+		letters: letters,
+		ctxFunc: template.EventCtxFuncWithTimeout(context.Background(), time.Second),
+	}
+
+	in := make(chan domain.Event)
+	out := make(chan domain.Event, 10)
+
+	go func() {
+		defer close(in)
+
+		update := domain.NewUpdateCompleted(domain.NewID("quotes", "AKRN"), date)
+		in <- domain.NewSourceRecovered(update, template.SourceISS)
+	}()
+
+	rule.Activate(in, out)
+	close(out)
+
+	var retries []domain.RetryRequested
+	for event := range out {
+		retries = append(retries, event.(domain.RetryRequested))
+	}
+
+	assert.Len(t, retries, 1, "Некорректное количество повторных обновлений")
+	assert.Equal(t, domain.NewID("quotes", "AKRN"), retries[0].ID(), "Повтор не пропущенного обновления")
+	assert.Equal(t, "QuotesRule", retries[0].Rule(), "Повтор не тем правилом")
+	assert.Equal(t, date, retries[0].Date(), "Повтор на другую дату")
+}
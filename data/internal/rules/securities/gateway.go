@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/WLM1ke/gomoex"
 	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
 	"sort"
 	"time"
 )
@@ -23,6 +24,11 @@ type gateway struct {
 	iss *gomoex.ISSClient
 }
 
+// Source - источник данных шлюза.
+func (g gateway) Source() string {
+	return template.SourceISS
+}
+
 func (g gateway) Get(
 	ctx context.Context,
 	_ domain.Table[gomoex.Security],
@@ -10,7 +10,6 @@ import (
 	"io"
 	"net/http"
 	"regexp"
-	"sort"
 	"time"
 )
 
@@ -26,6 +25,11 @@ type gateway struct {
 	client *http.Client
 }
 
+// Source - источник данных шлюза.
+func (g gateway) Source() string {
+	return template.SourceMOEX
+}
+
 func (g gateway) Get(
 	ctx context.Context,
 	_ domain.Table[DivStatus],
@@ -44,7 +48,7 @@ func (g gateway) Get(
 	if err != nil {
 		return nil, fmt.Errorf(
 			"%w: can't make request -> %s",
-			template.ErrRuleGateway,
+			template.ErrTransient,
 			err,
 		)
 	}
@@ -52,11 +56,7 @@ func (g gateway) Get(
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf(
-			"%w: bad respond status %s",
-			template.ErrRuleGateway,
-			resp.Status,
-		)
+		return nil, template.StatusError(resp)
 	}
 
 	decoder := charmap.Windows1251.NewDecoder()
@@ -112,20 +112,7 @@ func parceCSV(reader *csv.Reader, date time.Time) (rows []DivStatus, err error)
 		})
 	}
 
-	sort.Slice(
-		rows,
-		func(i, j int) bool {
-			if rows[i].Ticker < rows[j].Ticker {
-				return true
-			}
-
-			if (rows[i].Ticker == rows[j].Ticker) && rows[i].Date.Before(rows[j].Date) {
-				return true
-			}
-
-			return false
-		},
-	)
+	sortStatuses(rows)
 
 	return rows, nil
 }
@@ -0,0 +1,93 @@
+package status
+
+import (
+	"fmt"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
+	"github.com/WLM1ke/poptimizer/data/pkg/htmltable"
+	"regexp"
+	"sort"
+	"time"
+)
+
+const _htmlDateFormat = `2.01.2006`
+
+var (
+	reHTMLTicker = regexp.MustCompile(`\b([A-Z]+-[A-Z]+|[A-Z]{3,}[A-Z0-9]*)\b`)
+	reHTMLDate   = regexp.MustCompile(`\d{1,2}\.\d{2}\.\d{4}`)
+)
+
+// parseHTMLTable формирует отсортированный список ожидаемых дивидендов из строк html-таблицы.
+//
+// Первая строка должна быть заголовком, а столбцы с тикерами и датами закрытия реестра ищутся по подстрокам в их
+// названиях. В ячейке может быть несколько тикеров - для каждого создается отдельная строка. Строки без даты и
+// с датой ранее date пропускаются.
+func parseHTMLTable(rows [][]string, tickerCol, dateCol string, date time.Time) ([]DivStatus, error) {
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: empty html table", template.ErrRuleGateway)
+	}
+
+	tickerN, err := htmltable.Column(rows[0], tickerCol)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", template.ErrRuleGateway, err)
+	}
+
+	dateN, err := htmltable.Column(rows[0], dateCol)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", template.ErrRuleGateway, err)
+	}
+
+	var statuses []DivStatus
+
+	for _, row := range rows[1:] {
+		if len(row) <= tickerN || len(row) <= dateN {
+			continue
+		}
+
+		rawDate := reHTMLDate.FindString(row[dateN])
+		if rawDate == "" {
+			continue
+		}
+
+		divDate, err := time.Parse(_htmlDateFormat, rawDate)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"%w: can't parse date %s ->  %s",
+				template.ErrRuleGateway,
+				rawDate,
+				err,
+			)
+		}
+
+		if divDate.Before(date.AddDate(0, 0, -_pastDays)) {
+			continue
+		}
+
+		for _, ticker := range reHTMLTicker.FindAllString(row[tickerN], -1) {
+			statuses = append(statuses, DivStatus{
+				Ticker: ticker,
+				Date:   divDate,
+			})
+		}
+	}
+
+	sortStatuses(statuses)
+
+	return statuses, nil
+}
+
+func sortStatuses(rows []DivStatus) {
+	sort.Slice(
+		rows,
+		func(i, j int) bool {
+			if rows[i].Ticker < rows[j].Ticker {
+				return true
+			}
+
+			if (rows[i].Ticker == rows[j].Ticker) && rows[i].Date.Before(rows[j].Date) {
+				return true
+			}
+
+			return false
+		},
+	)
+}
@@ -0,0 +1,42 @@
+package status
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHTML(t *testing.T) {
+	date := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := func(month time.Month, day int) time.Time { return time.Date(2022, month, day, 0, 0, 0, 0, time.UTC) }
+
+	tbl := []struct {
+		file  string
+		parse func(file *os.File) ([]DivStatus, error)
+		rows  []DivStatus
+	}{
+		{
+			"testdata/smart_lab.html",
+			func(file *os.File) ([]DivStatus, error) { return parseSmartLab(file, date) },
+			[]DivStatus{{"AKRN", day(time.June, 17)}, {"VEON-RX", day(time.June, 2)}},
+		},
+		{
+			"testdata/reestry.html",
+			func(file *os.File) ([]DivStatus, error) { return parseReestry(file, date) },
+			[]DivStatus{{"T-RM", day(time.June, 20)}, {"TATN", day(time.July, 7)}, {"TATNP", day(time.July, 7)}},
+		},
+	}
+
+	for _, testCase := range tbl {
+		file, err := os.Open(testCase.file)
+		assert.Nil(t, err, "Не удалось открыть файл с данными")
+
+		rows, err := testCase.parse(file)
+		file.Close()
+
+		assert.Nil(t, err, "Ошибка при разборе страницы")
+		assert.Equal(t, testCase.rows, rows, "Некорректные данные о дивидендах")
+	}
+}
@@ -0,0 +1,256 @@
+package status
+
+import (
+	"errors"
+	"fmt"
+	"github.com/WLM1ke/gomoex"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/securities"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
+	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrSourcesMismatch - расхождение списков тикеров с ожидаемыми дивидендами в разных источниках.
+var ErrSourcesMismatch = errors.New("dividend status sources mismatch")
+
+// sources - таблицы с ожидаемыми дивидендами из разных источников, которые сверяются между собой.
+var sources = []domain.ID{ID, SmartLabID, ReestryID}
+
+// reconcile - правило сверки ожидаемых дивидендов из разных источников.
+//
+// Сверка осуществляется после каждого обновления источника на очередную дату между источниками, обновленными на эту
+// дату, поэтому недоступность одного из источников не мешает сверке остальных. Учитываются только тикеры из таблицы
+// securities. Об одном и том же расхождении сообщается один раз, пока оно не будет устранено.
+type reconcile struct {
+	logger     *lgr.Logger
+	status     repo.Read[DivStatus]
+	securities repo.Read[gomoex.Security]
+	ctxFunc    template.EventCtxFunc
+
+	updated map[domain.ID]time.Time
+	last    time.Time
+	// reported расхождения, о которых уже сообщено
+	reported map[mismatch]bool
+}
+
+// mismatch - тикер, отсутствующий в источнике, хотя он есть в других.
+type mismatch struct {
+	ticker string
+	source domain.Name
+}
+
+func (r *reconcile) Subscriptions() []domain.Subscription {
+	subs := make([]domain.Subscription, 0, len(sources))
+	for _, id := range sources {
+		subs = append(subs, domain.Subscribe[domain.UpdateCompleted](id))
+	}
+
+	return subs
+}
+
+// Name - название правила.
+func (r *reconcile) Name() string {
+	return "DivStatusReconcileRule"
+}
+
+// Produces - правило не обновляет таблицы.
+func (r *reconcile) Produces() []domain.ID {
+	return nil
+}
+
+func (r *reconcile) Activate(in <-chan domain.Event, out chan<- domain.Event) {
+	r.logger.Infof("DivStatusReconcileRule: started")
+	defer r.logger.Infof("DivStatusReconcileRule: stopped")
+
+	for event := range in {
+		event, ok := event.(domain.UpdateCompleted)
+		if !ok {
+			continue
+		}
+
+		available, missing := r.available(event)
+		if len(available) < 2 {
+			continue
+		}
+
+		if err := r.process(available, missing); err != nil {
+			out <- domain.NewErrorOccurred(event, err)
+		}
+	}
+}
+
+// available разделяет источники на обновленные на дату последнего обновления и пока не обновленные.
+//
+// Обновление источника на более раннюю дату не приводит к сверке.
+func (r *reconcile) available(event domain.UpdateCompleted) (available, missing []domain.ID) {
+	r.updated[event.ID()] = event.Date()
+
+	if event.Date().Before(r.last) {
+		return nil, nil
+	}
+
+	r.last = event.Date()
+
+	for _, id := range sources {
+		if r.updated[id].Equal(r.last) {
+			available = append(available, id)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+
+	return available, missing
+}
+
+func (r *reconcile) process(available, missing []domain.ID) error {
+	ctx, cancel := r.ctxFunc()
+	defer cancel()
+
+	secs, err := r.securities.Get(ctx, securities.ID)
+	if err != nil {
+		return err
+	}
+
+	tickers := make(map[string][]domain.Name, len(secs.Rows()))
+	for _, sec := range secs.Rows() {
+		tickers[sec.Ticker] = nil
+	}
+
+	for _, id := range available {
+		table, err := r.status.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		for _, row := range table.Rows() {
+			found, ok := tickers[row.Ticker]
+			if ok && (len(found) == 0 || found[len(found)-1] != id.Name()) {
+				tickers[row.Ticker] = append(found, id.Name())
+			}
+		}
+	}
+
+	fresh := r.remember(findMismatches(tickers, available), available)
+
+	r.logger.Infof(
+		"DivStatusReconcileRule: %s reconciled, %s not updated",
+		joinNames(available),
+		joinNames(missing),
+	)
+
+	if len(fresh) == 0 {
+		return nil
+	}
+
+	if len(missing) != 0 {
+		return fmt.Errorf(
+			"%w: %s, not updated %s",
+			ErrSourcesMismatch,
+			describe(fresh),
+			joinNames(missing),
+		)
+	}
+
+	return fmt.Errorf("%w: %s", ErrSourcesMismatch, describe(fresh))
+}
+
+// remember запоминает текущие расхождения сверенных источников и возвращает те, о которых еще не сообщалось.
+//
+// Расхождения с не участвовавшими в сверке источниками сохраняются до их сверки.
+func (r *reconcile) remember(mismatches []mismatch, available []domain.ID) (fresh []mismatch) {
+	reported := make(map[mismatch]bool, len(mismatches))
+
+	for m := range r.reported {
+		if !containsID(available, m.source) {
+			reported[m] = true
+		}
+	}
+
+	for _, m := range mismatches {
+		if !r.reported[m] {
+			fresh = append(fresh, m)
+		}
+
+		reported[m] = true
+	}
+
+	r.reported = reported
+
+	return fresh
+}
+
+// findMismatches находит отсортированные по тикерам и источникам пропуски тикеров, которые есть только в части
+// сверяемых источников.
+func findMismatches(tickers map[string][]domain.Name, available []domain.ID) (mismatches []mismatch) {
+	for ticker, found := range tickers {
+		if len(found) == 0 || len(found) == len(available) {
+			continue
+		}
+
+		for _, id := range available {
+			if !contains(found, id.Name()) {
+				mismatches = append(mismatches, mismatch{ticker: ticker, source: id.Name()})
+			}
+		}
+	}
+
+	sort.SliceStable(mismatches, func(i, j int) bool { return mismatches[i].ticker < mismatches[j].ticker })
+
+	return mismatches
+}
+
+// describe формирует описание пропусков с объединением источников для одного тикера.
+func describe(mismatches []mismatch) string {
+	var (
+		parts   []string
+		missing []string
+	)
+
+	for n, m := range mismatches {
+		missing = append(missing, string(m.source))
+
+		if n+1 == len(mismatches) || mismatches[n+1].ticker != m.ticker {
+			parts = append(parts, fmt.Sprintf("%s missing in %s", m.ticker, strings.Join(missing, "/")))
+			missing = nil
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+func joinNames(ids []domain.ID) string {
+	if len(ids) == 0 {
+		return "none"
+	}
+
+	names := make([]string, 0, len(ids))
+	for _, id := range ids {
+		names = append(names, string(id.Name()))
+	}
+
+	return strings.Join(names, "/")
+}
+
+func contains(names []domain.Name, name domain.Name) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsID(ids []domain.ID, name domain.Name) bool {
+	for _, id := range ids {
+		if id.Name() == name {
+			return true
+		}
+	}
+
+	return false
+}
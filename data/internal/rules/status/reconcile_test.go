@@ -0,0 +1,112 @@
+package status
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/WLM1ke/gomoex"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/securities"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
+	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeRead[R any] struct {
+	tables map[domain.ID]domain.Table[R]
+}
+
+func (f fakeRead[R]) Get(_ context.Context, id domain.ID) (domain.Table[R], error) {
+	if table, ok := f.tables[id]; ok {
+		return table, nil
+	}
+
+	return domain.NewEmptyTable[R](id), nil
+}
+
+func TestFindMismatches(t *testing.T) {
+	tickers := map[string][]domain.Name{
+		"AKRN": {ID.Name(), SmartLabID.Name(), ReestryID.Name()},
+		"TATN": {ID.Name()},
+		"GAZP": nil,
+		"T-RM": {SmartLabID.Name(), ReestryID.Name()},
+	}
+
+	assert.Equal(
+		t,
+		"T-RM missing in status, TATN missing in smart_lab/reestry",
+		describe(findMismatches(tickers, sources)),
+		"Некорректные расхождения источников",
+	)
+
+	assert.Equal(
+		t,
+		"TATN missing in smart_lab",
+		describe(findMismatches(map[string][]domain.Name{"TATN": {ID.Name()}}, []domain.ID{ID, SmartLabID})),
+		"Некорректные расхождения части источников",
+	)
+}
+
+func TestReconcileReportsOnlyNewMismatches(t *testing.T) {
+	date := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := func(tickers ...string) domain.Table[DivStatus] {
+		divs := make([]DivStatus, 0, len(tickers))
+		for _, ticker := range tickers {
+			divs = append(divs, DivStatus{Ticker: ticker, Date: date})
+		}
+
+		return domain.NewTable(ID, date, divs)
+	}
+
+	secs := []gomoex.Security{{Ticker: "AKRN"}, {Ticker: "T-RM"}, {Ticker: "TATN"}}
+	rule := &reconcile{
+		logger: lgr.NoOp(),
+		status: fakeRead[DivStatus]{tables: map[domain.ID]domain.Table[DivStatus]{
+			ID:         rows("AKRN", "TATN"),
+			SmartLabID: rows("AKRN", "T-RM"),
+			ReestryID:  rows("AKRN", "T-RM", "TATN"),
+		}},
+		securities: fakeRead[gomoex.Security]{tables: map[domain.ID]domain.Table[gomoex.Security]{
+			securities.ID: domain.NewTable(securities.ID, date, secs),
+		}},
+		ctxFunc:  template.EventCtxFuncWithTimeout(context.Background(), time.Second),
+		updated:  make(map[domain.ID]time.Time),
+		reported: make(map[mismatch]bool),
+	}
+
+	next := date.AddDate(0, 0, 1)
+	events := []domain.Event{
+		domain.NewUpdateCompleted(ID, date),
+		domain.NewUpdateCompleted(SmartLabID, date),
+		domain.NewUpdateCompleted(ReestryID, date),
+		domain.NewUpdateCompleted(ID, next),
+		domain.NewUpdateCompleted(ReestryID, date),
+		domain.NewUpdateCompleted(SmartLabID, next),
+	}
+
+	in := make(chan domain.Event, len(events))
+	out := make(chan domain.Event, len(events))
+
+	for _, event := range events {
+		in <- event
+	}
+
+	close(in)
+	rule.Activate(in, out)
+	close(out)
+
+	var errs []string
+	for event := range out {
+		errs = append(errs, event.(domain.ErrorOccurred).Err().Error())
+	}
+
+	assert.Equal(
+		t,
+		[]string{
+			"dividend status sources mismatch: T-RM missing in status, TATN missing in smart_lab, not updated reestry",
+		},
+		errs,
+		"Некорректные сообщения о расхождениях",
+	)
+}
@@ -0,0 +1,53 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
+	"github.com/WLM1ke/poptimizer/data/pkg/htmltable"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	_reestrySource = "Reestry"
+	// Адрес https://закрытияреестров.рф в punycode.
+	_reestryURL       = `https://xn--80aeiahhn9aobclif2kuc.xn--p1ai/`
+	_reestryTable     = 0
+	_reestryTickerCol = `Тикер`
+	_reestryDateCol   = `закрытия реестра`
+)
+
+// ReestryID - таблица ожидаемых дивидендов по данным закрытияреестров.рф.
+var ReestryID = domain.NewID(_group, "reestry")
+
+type reestryGateway struct {
+	client *http.Client
+}
+
+// Source - источник данных шлюза.
+func (g reestryGateway) Source() string {
+	return _reestrySource
+}
+
+func (g reestryGateway) Get(ctx context.Context, _ domain.Table[DivStatus], date time.Time) ([]DivStatus, error) {
+	body, err := getHTML(ctx, g.client, _reestryURL)
+	if err != nil {
+		return nil, err
+	}
+
+	defer body.Close()
+
+	return parseReestry(body, date)
+}
+
+func parseReestry(reader io.Reader, date time.Time) ([]DivStatus, error) {
+	rows, err := htmltable.Parse(reader, _reestryTable)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", template.ErrRuleGateway, err)
+	}
+
+	return parseHTMLTable(rows, _reestryTickerCol, _reestryDateCol, date)
+}
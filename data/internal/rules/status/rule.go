@@ -1,6 +1,7 @@
 package status
 
 import (
+	"github.com/WLM1ke/gomoex"
 	"github.com/WLM1ke/poptimizer/data/internal/domain"
 	"github.com/WLM1ke/poptimizer/data/internal/repo"
 	"github.com/WLM1ke/poptimizer/data/internal/rules/dates"
@@ -13,6 +14,7 @@ import (
 
 const _group = "status"
 
+// ID - таблица ожидаемых дивидендов по данным MOEX.
 var ID = domain.NewID(_group, _group)
 
 type DivStatus struct {
@@ -20,13 +22,11 @@ type DivStatus struct {
 	Date   time.Time
 }
 
-// Возможные альтернативные источники:
-// - https://smart-lab.ru/dividends/index/order_by_yield/desc/
-// - https://закрытияреестров.рф
-// - https://dividend.company
+// New создает правило обновления ожидаемых дивидендов по данным MOEX.
+//
 // Теоретически можно проверять коректность распознования тикеров, но в этом кажется нет необходимости
 // Тесты для VEON-RX, AKRN и T-RM
-func New(logger *lgr.Logger, db *mongo.Database, client *http.Client, timeout time.Duration) domain.Rule {
+func New(logger *lgr.Logger, db *mongo.Database, client *http.Client, opts template.Options) domain.Rule {
 	return template.NewRule[DivStatus](
 		"DivStatus",
 		logger,
@@ -35,6 +35,48 @@ func New(logger *lgr.Logger, db *mongo.Database, client *http.Client, timeout ti
 		gateway{client: client},
 		validator,
 		false,
-		template.EventCtxFuncWithTimeout(timeout),
+		opts,
 	)
 }
+
+// NewSmartLab создает правило обновления ожидаемых дивидендов по данным https://smart-lab.ru.
+func NewSmartLab(logger *lgr.Logger, db *mongo.Database, client *http.Client, opts template.Options) domain.Rule {
+	return template.NewRule[DivStatus](
+		"DivStatusSmartLab",
+		logger,
+		repo.NewMongo[DivStatus](db),
+		template.NewSelectOnTableUpdate(dates.ID, SmartLabID),
+		smartLabGateway{client: client},
+		validator,
+		false,
+		opts,
+	)
+}
+
+// NewReestry создает правило обновления ожидаемых дивидендов по данным https://закрытияреестров.рф.
+func NewReestry(logger *lgr.Logger, db *mongo.Database, client *http.Client, opts template.Options) domain.Rule {
+	return template.NewRule[DivStatus](
+		"DivStatusReestry",
+		logger,
+		repo.NewMongo[DivStatus](db),
+		template.NewSelectOnTableUpdate(dates.ID, ReestryID),
+		reestryGateway{client: client},
+		validator,
+		false,
+		opts,
+	)
+}
+
+// NewReconcile создает правило сверки ожидаемых дивидендов из разных источников.
+//
+// Новые расхождения - тикеры, которые присутствуют только в части источников, - сообщаются в виде события-ошибки.
+func NewReconcile(logger *lgr.Logger, db *mongo.Database, ctxFunc template.EventCtxFunc) domain.Rule {
+	return &reconcile{
+		logger:     logger,
+		status:     repo.NewMongo[DivStatus](db),
+		securities: repo.NewMongo[gomoex.Security](db),
+		ctxFunc:    ctxFunc,
+		updated:    make(map[domain.ID]time.Time),
+		reported:   make(map[mismatch]bool),
+	}
+}
@@ -0,0 +1,80 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
+	"github.com/WLM1ke/poptimizer/data/pkg/htmltable"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	_smartLabSource    = "SmartLab"
+	_smartLabURL       = `https://smart-lab.ru/dividends/index/order_by_yield/desc/`
+	_smartLabTable     = 0
+	_smartLabTickerCol = `Тикер`
+	_smartLabDateCol   = `закрытия реестра`
+)
+
+// SmartLabID - таблица ожидаемых дивидендов по данным smart-lab.ru.
+var SmartLabID = domain.NewID(_group, "smart_lab")
+
+type smartLabGateway struct {
+	client *http.Client
+}
+
+// Source - источник данных шлюза.
+func (g smartLabGateway) Source() string {
+	return _smartLabSource
+}
+
+func (g smartLabGateway) Get(ctx context.Context, _ domain.Table[DivStatus], date time.Time) ([]DivStatus, error) {
+	body, err := getHTML(ctx, g.client, _smartLabURL)
+	if err != nil {
+		return nil, err
+	}
+
+	defer body.Close()
+
+	return parseSmartLab(body, date)
+}
+
+func parseSmartLab(reader io.Reader, date time.Time) ([]DivStatus, error) {
+	rows, err := htmltable.Parse(reader, _smartLabTable)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", template.ErrRuleGateway, err)
+	}
+
+	return parseHTMLTable(rows, _smartLabTickerCol, _smartLabDateCol, date)
+}
+
+func getHTML(ctx context.Context, client *http.Client, url string) (io.ReadCloser, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"%w: can't create request -> %s",
+			template.ErrRuleGateway,
+			err,
+		)
+	}
+
+	resp, err := client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"%w: can't make request -> %s",
+			template.ErrTransient,
+			err,
+		)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+
+		return nil, template.StatusError(resp)
+	}
+
+	return resp.Body, nil
+}
@@ -0,0 +1,141 @@
+// Package tables содержит правило, отслеживающее состояние обновления таблиц.
+package tables
+
+import (
+	"context"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/end"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
+	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+// statuses - хранилище состояния обновления таблиц.
+type statuses interface {
+	Completed(ctx context.Context, id domain.ID, date, at time.Time) error
+	Checked(ctx context.Context, id domain.ID, at time.Time) error
+	Failed(ctx context.Context, id domain.ID, err error, at time.Time) error
+}
+
+// Rule - правило, сохраняющее время последнего обновления, проверки и ошибки для каждой таблицы.
+//
+// Учитываются только результаты обновлений, начатых обновляющим таблицу правилом, поэтому сообщения о проблемах
+// в данных, относящиеся к таблице, например, расхождения источников или аномалии рядов, не считаются ошибками ее
+// обновления.
+type Rule struct {
+	logger   *lgr.Logger
+	statuses statuses
+	ctxFunc  template.EventCtxFunc
+
+	// started - идентификатор события о выполняющемся обновлении каждой таблицы
+	started map[domain.ID]string
+}
+
+// New создает правило отслеживания состояния обновления таблиц.
+func New(logger *lgr.Logger, db *mongo.Database, ctxFunc template.EventCtxFunc) *Rule {
+	return &Rule{
+		logger:   logger,
+		statuses: repo.NewTableStatuses(db),
+		ctxFunc:  ctxFunc,
+		started:  make(map[domain.ID]string),
+	}
+}
+
+// Subscriptions - события о результатах обновления таблиц.
+func (r *Rule) Subscriptions() []domain.Subscription {
+	return []domain.Subscription{
+		domain.SubscribeAll[domain.UpdateStarted](),
+		domain.SubscribeAll[domain.UpdateCompleted](),
+		domain.SubscribeAll[domain.UpdateSkipped](),
+		domain.SubscribeAll[domain.GatewayFailed](),
+		domain.SubscribeAll[domain.ValidationFailed](),
+		domain.SubscribeAll[domain.ErrorOccurred](),
+	}
+}
+
+// Name - название правила.
+func (r *Rule) Name() string {
+	return "TableStatusRule"
+}
+
+// Produces - правило не обновляет таблицы.
+func (r *Rule) Produces() []domain.ID {
+	return nil
+}
+
+func (r *Rule) Activate(in <-chan domain.Event, _ chan<- domain.Event) {
+	r.logger.Infof("TableStatusRule: started")
+	defer r.logger.Infof("TableStatusRule: stopped")
+
+	for event := range in {
+		ctx, cancel := r.ctxFunc()
+
+		if err := r.save(ctx, event); err != nil {
+			r.logger.Warnf("TableStatusRule: can't save status for %s -> %s", event, err)
+		}
+
+		cancel()
+	}
+}
+
+// Stored проверяет, что идентификатор обозначает хранимую таблицу, а не событие без данных - окончание торгового дня
+// или изменение доступности внешнего источника.
+func Stored(id domain.ID) bool {
+	switch id.Group() {
+	case end.ID.Group(), domain.SourceID("").Group():
+		return false
+	default:
+		return true
+	}
+}
+
+func (r *Rule) save(ctx context.Context, event domain.Event) error {
+	if !Stored(event.ID()) {
+		return nil
+	}
+
+	now := time.Now()
+
+	switch typed := event.(type) {
+	case domain.UpdateStarted:
+		r.started[typed.ID()] = typed.Trace().Causation
+
+		return nil
+	case domain.UpdateCompleted:
+		if !r.finished(typed.ID(), typed.Trace().ID) {
+			return nil
+		}
+
+		return r.statuses.Completed(ctx, typed.ID(), typed.Date(), now)
+	}
+
+	if !r.finished(event.ID(), event.Trace().Causation) {
+		return nil
+	}
+
+	switch typed := event.(type) {
+	case domain.UpdateSkipped:
+		return r.statuses.Checked(ctx, typed.ID(), now)
+	case domain.GatewayFailed:
+		return r.statuses.Failed(ctx, typed.ID(), typed.Err(), now)
+	case domain.ValidationFailed:
+		return r.statuses.Failed(ctx, typed.ID(), typed.Err(), now)
+	case domain.ErrorOccurred:
+		return r.statuses.Failed(ctx, typed.ID(), typed.Err(), now)
+	default:
+		return nil
+	}
+}
+
+// finished проверяет, что событие завершает начатое обновление таблицы update, и забывает о нем.
+func (r *Rule) finished(id domain.ID, update string) bool {
+	if update == "" || r.started[id] != update {
+		return false
+	}
+
+	delete(r.started, id)
+
+	return true
+}
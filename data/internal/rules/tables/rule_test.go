@@ -0,0 +1,110 @@
+package tables
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/end"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
+	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeStatuses запоминает вид последней отметки для каждой таблицы.
+type fakeStatuses struct {
+	marks map[domain.ID]string
+}
+
+func (f *fakeStatuses) Completed(_ context.Context, id domain.ID, _, _ time.Time) error {
+	f.marks[id] = "completed"
+
+	return nil
+}
+
+func (f *fakeStatuses) Checked(_ context.Context, id domain.ID, _ time.Time) error {
+	f.marks[id] = "checked"
+
+	return nil
+}
+
+func (f *fakeStatuses) Failed(_ context.Context, id domain.ID, _ error, _ time.Time) error {
+	f.marks[id] = "failed"
+
+	return nil
+}
+
+func newTestRule(statuses *fakeStatuses) *Rule {
+	return &Rule{
+		logger:   lgr.NoOp(),
+		statuses: statuses,
+		ctxFunc:  template.EventCtxFuncWithTimeout(context.Background(), time.Second),
+		started:  make(map[domain.ID]string),
+	}
+}
+
+func runRule(rule *Rule, events ...domain.Event) {
+	in := make(chan domain.Event)
+
+	go func() {
+		defer close(in)
+
+		for _, event := range events {
+			in <- event
+		}
+	}()
+
+	rule.Activate(in, nil)
+}
+
+func TestRuleSkipsPseudoTables(t *testing.T) {
+	date := time.Date(2022, time.January, 10, 0, 0, 0, 0, time.UTC)
+	quotes := domain.NewUpdateCompleted(domain.NewID("quotes", "AKRN"), date)
+	dividends := domain.NewUpdateCompleted(domain.NewID("dividends", "AKRN"), date)
+	day := domain.NewUpdateCompleted(end.ID, date)
+
+	statuses := fakeStatuses{marks: make(map[domain.ID]string)}
+
+	runRule(
+		newTestRule(&statuses),
+		domain.NewUpdateStarted(day, "DayEndedRule"),
+		day,
+		domain.NewSourceRecovered(day, template.SourceISS),
+		domain.NewUpdateStarted(quotes, "QuotesRule"),
+		domain.NewUpdateStarted(dividends, "DividendsRule"),
+		domain.NewUpdateSkipped(dividends),
+		domain.NewErrorOccurred(quotes, errors.New("test")),
+	)
+
+	assert.Equal(t, map[domain.ID]string{quotes.ID(): "failed", dividends.ID(): "checked"}, statuses.marks)
+}
+
+func TestRuleRecordsOnlyOwnUpdates(t *testing.T) {
+	date := time.Date(2022, time.January, 10, 0, 0, 0, 0, time.UTC)
+	status := domain.NewUpdateCompleted(domain.NewID("status", "status"), date)
+	quotes := domain.NewUpdateCompleted(domain.NewID("quotes", "AKRN"), date)
+
+	statuses := fakeStatuses{marks: make(map[domain.ID]string)}
+
+	runRule(
+		newTestRule(&statuses),
+		// Обновление без начала, например, запрошенное вручную
+		quotes,
+		domain.NewErrorOccurred(quotes, errors.New("test")),
+		// Сообщение о расхождении источников после завершения обновления
+		domain.NewUpdateStarted(status, "StatusRule"),
+		status,
+		domain.NewErrorOccurred(status, errors.New("mismatch")),
+	)
+
+	assert.Equal(t, map[domain.ID]string{status.ID(): "completed"}, statuses.marks)
+}
+
+func TestStored(t *testing.T) {
+	assert.False(t, Stored(end.ID))
+	assert.False(t, Stored(end.CPIID))
+	assert.False(t, Stored(domain.SourceID(template.SourceISS)))
+	assert.True(t, Stored(domain.NewID("cpi", "cpi")))
+}
@@ -0,0 +1,93 @@
+package template
+
+import (
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"time"
+)
+
+// BatchFunc обрабатывает накопленные обновления таблиц и возвращает вызванные ими события.
+type BatchFunc func(batch []domain.UpdateCompleted) []domain.Event
+
+// ActivateBatched накапливает отобранные обновления таблиц и обрабатывает их вместе, когда поток обновлений затихает
+// на delay, а также после закрытия входящего канала.
+//
+// Предназначено для правил, которые сводят обновления многих таблиц в одну общую таблицу и не должны перезаписывать
+// ее после каждого обновления. Обработка событий подтверждается после обработки содержащей их группы, а не
+// отобранных событий - сразу.
+func ActivateBatched(
+	in <-chan domain.Event,
+	out chan<- domain.Event,
+	ack func(domain.Event),
+	delay time.Duration,
+	selected func(update domain.UpdateCompleted) bool,
+	process BatchFunc,
+) {
+	timer := time.NewTimer(delay)
+	timer.Stop()
+
+	defer timer.Stop()
+
+	var batch []domain.UpdateCompleted
+
+	flush := func() {
+		for _, newEvent := range process(batch) {
+			out <- newEvent
+		}
+
+		for _, event := range batch {
+			ack(event)
+		}
+
+		batch = nil
+	}
+
+	for {
+		select {
+		case event, ok := <-in:
+			if !ok {
+				if len(batch) != 0 {
+					flush()
+				}
+
+				return
+			}
+
+			update, ok := event.(domain.UpdateCompleted)
+			if !ok || !selected(update) {
+				ack(event)
+
+				continue
+			}
+
+			batch = append(batch, update)
+
+			// Таймер мог сработать, пока обрабатывалось событие, тогда его сигнал нужно удалить перед перезапуском
+			if !timer.Stop() && len(batch) > 1 {
+				<-timer.C
+			}
+
+			timer.Reset(delay)
+		case <-timer.C:
+			flush()
+		}
+	}
+}
+
+// Latest оставляет по одному последнему обновлению каждой таблицы в порядке их первого появления.
+func Latest(batch []domain.UpdateCompleted) []domain.UpdateCompleted {
+	index := make(map[domain.ID]int, len(batch))
+	unique := make([]domain.UpdateCompleted, 0, len(batch))
+
+	for _, event := range batch {
+		if n, ok := index[event.ID()]; ok {
+			unique[n] = event
+
+			continue
+		}
+
+		index[event.ID()] = len(unique)
+		unique = append(unique, event)
+	}
+
+	return unique
+}
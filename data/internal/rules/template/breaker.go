@@ -0,0 +1,188 @@
+package template
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrSourceUnavailable - обращение к источнику пропущено, так как он признан временно недоступным.
+var ErrSourceUnavailable = fmt.Errorf("%w: source unavailable", ErrRuleGateway)
+
+// Названия внешних источников данных, используемых несколькими правилами.
+const (
+	SourceISS  = "MOEX ISS"
+	SourceMOEX = "MOEX"
+)
+
+// Sourced - шлюз, получающий данные из внешнего источника.
+//
+// Обращения к одному источнику из всех правил проходят через общий предохранитель.
+type Sourced interface {
+	Source() string
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// breaker - предохранитель отдельного источника данных.
+//
+// Размыкается после заданного количества временных ошибок подряд. В разомкнутом состоянии запрещает обращения
+// к источнику, а после паузы пропускает одно пробное обращение, успех которого замыкает предохранитель. Остальные
+// обращения дожидаются результата пробного.
+type breaker struct {
+	lock     sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	// probe закрывается по окончании пробного обращения
+	probe chan struct{}
+}
+
+// finishProbe сообщает ожидающим обращениям об окончании пробного обращения.
+func (br *breaker) finishProbe() {
+	if br.probe != nil {
+		close(br.probe)
+		br.probe = nil
+	}
+}
+
+// Breakers - предохранители внешних источников данных.
+type Breakers struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	lock     sync.Mutex
+	breakers map[string]*breaker
+}
+
+// NewBreakers создает предохранители, размыкающиеся после threshold временных ошибок подряд на время cooldown.
+func NewBreakers(threshold int, cooldown time.Duration) *Breakers {
+	return &Breakers{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+		breakers:  make(map[string]*breaker),
+	}
+}
+
+func (b *Breakers) get(source string) *breaker {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	br, ok := b.breakers[source]
+	if !ok {
+		br = &breaker{}
+		b.breakers[source] = br
+	}
+
+	return br
+}
+
+// allow проверяет, можно ли обратиться к источнику.
+//
+// Во время пробного обращения ожидает его результата, поэтому может вернуть ошибку контекста.
+func (b *Breakers) allow(ctx context.Context, source string) error {
+	br := b.get(source)
+
+	for {
+		br.lock.Lock()
+
+		switch br.state {
+		case breakerOpen:
+			if b.now().Sub(br.openedAt) < b.cooldown {
+				br.lock.Unlock()
+
+				return fmt.Errorf("%w: %s", ErrSourceUnavailable, source)
+			}
+
+			br.state = breakerHalfOpen
+			br.probe = make(chan struct{})
+			br.lock.Unlock()
+
+			return nil
+		case breakerHalfOpen:
+			probe := br.probe
+			br.lock.Unlock()
+
+			select {
+			case <-probe:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		default:
+			br.lock.Unlock()
+
+			return nil
+		}
+	}
+}
+
+// success учитывает удачное обращение и возвращает true, если оно замкнуло предохранитель.
+func (b *Breakers) success(source string) bool {
+	br := b.get(source)
+
+	br.lock.Lock()
+	defer br.lock.Unlock()
+
+	recovered := br.state != breakerClosed
+	br.state = breakerClosed
+	br.failures = 0
+	br.finishProbe()
+
+	return recovered
+}
+
+// failure учитывает временную ошибку и возвращает true, если она разомкнула замкнутый предохранитель.
+//
+// Неудачное пробное обращение повторно размыкает предохранитель без повторного сообщения о недоступности.
+func (b *Breakers) failure(source string) bool {
+	br := b.get(source)
+
+	br.lock.Lock()
+	defer br.lock.Unlock()
+
+	switch br.state {
+	case breakerHalfOpen:
+		br.state = breakerOpen
+		br.openedAt = b.now()
+		br.finishProbe()
+
+		return false
+	case breakerOpen:
+		return false
+	default:
+		br.failures++
+		if br.failures < b.threshold {
+			return false
+		}
+
+		br.state = breakerOpen
+		br.openedAt = b.now()
+
+		return true
+	}
+}
+
+// release учитывает обращение, не позволившее судить о доступности источника, например, прерванное остановкой.
+//
+// Прерванное пробное обращение возвращает предохранитель в разомкнутое состояние без новой паузы, чтобы следующее
+// обращение стало пробным.
+func (b *Breakers) release(source string) {
+	br := b.get(source)
+
+	br.lock.Lock()
+	defer br.lock.Unlock()
+
+	if br.state == breakerHalfOpen {
+		br.state = breakerOpen
+		br.finishProbe()
+	}
+}
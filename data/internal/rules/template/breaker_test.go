@@ -0,0 +1,70 @@
+package template
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreakers(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	breakers := NewBreakers(2, time.Minute)
+	breakers.now = func() time.Time { return now }
+
+	assert.Nil(t, breakers.allow(ctx, SourceISS))
+	assert.False(t, breakers.failure(SourceISS))
+	assert.Nil(t, breakers.allow(ctx, SourceISS))
+	assert.True(t, breakers.failure(SourceISS), "opens after threshold")
+	assert.ErrorIs(t, breakers.allow(ctx, SourceISS), ErrSourceUnavailable)
+	assert.Nil(t, breakers.allow(ctx, SourceMOEX), "sources are independent")
+
+	now = now.Add(time.Minute)
+
+	assert.Nil(t, breakers.allow(ctx, SourceISS), "trial after cooldown")
+	assert.False(t, breakers.failure(SourceISS), "failed trial reopens silently")
+	assert.ErrorIs(t, breakers.allow(ctx, SourceISS), ErrSourceUnavailable)
+
+	now = now.Add(time.Minute)
+
+	assert.Nil(t, breakers.allow(ctx, SourceISS))
+	breakers.release(SourceISS)
+	assert.Nil(t, breakers.allow(ctx, SourceISS), "released trial is repeated without cooldown")
+	assert.True(t, breakers.success(SourceISS), "recovers once")
+	assert.False(t, breakers.success(SourceISS))
+	assert.Nil(t, breakers.allow(ctx, SourceISS))
+}
+
+func TestBreakersWaitForTrial(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	breakers := NewBreakers(1, time.Minute)
+	breakers.now = func() time.Time { return now }
+
+	breakers.failure(SourceISS)
+
+	now = now.Add(time.Minute)
+
+	assert.Nil(t, breakers.allow(context.Background(), SourceISS), "trial after cooldown")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t, breakers.allow(ctx, SourceISS), context.DeadlineExceeded, "waits for trial")
+
+	allowed := make(chan error)
+
+	for i := 0; i < 2; i++ {
+		go func() {
+			allowed <- breakers.allow(context.Background(), SourceISS)
+		}()
+	}
+
+	breakers.success(SourceISS)
+
+	assert.Nil(t, <-allowed, "allowed after successful trial")
+	assert.Nil(t, <-allowed, "allowed after successful trial")
+}
@@ -0,0 +1,107 @@
+package template
+
+import (
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"sync"
+	"time"
+)
+
+// coalescer последовательно выполняет обновления каждой таблицы и объединяет накопившиеся запросы.
+//
+// Пока таблица обновляется, запросы с датой раньше даты выполняющегося обновления отбрасываются, а из остальных
+// запоминается только запрос с самой поздней датой. После завершения текущего обновления он выполняется той же
+// горутиной. Запросы с той же датой не отбрасываются, так как могут быть вызваны данными, появившимися после начала
+// выполняющегося обновления (например, дивидендами при обновлении по котировкам), но все они объединяются в одно
+// повторное обновление.
+type coalescer struct {
+	lock    sync.Mutex
+	flights map[domain.ID]*flight
+}
+
+// flight - выполняющееся обновление таблицы и отложенный запрос на ее обновление.
+type flight struct {
+	date time.Time
+	// done закрывается после выполнения обновления
+	done chan struct{}
+	// dropped количество отброшенных запросов, ожидающих выполнения обновления
+	dropped int
+	next    *queued
+}
+
+// queued - отложенный запрос на обновление таблицы и сигнал о его выполнении для объединенных с ним запросов.
+type queued struct {
+	update domain.UpdateCompleted
+	done   chan struct{}
+	// merged количество объединенных запросов
+	merged int
+}
+
+func newCoalescer() *coalescer {
+	return &coalescer{flights: make(map[domain.ID]*flight)}
+}
+
+// run выполняет обновление, если таблица не обновляется, и возвращает false, если запрос объединен с другими.
+//
+// Объединенный запрос возвращает управление только после выполнения учитывающего его обновления.
+func (c *coalescer) run(update domain.UpdateCompleted, handle func(update domain.UpdateCompleted)) bool {
+	acquired, wait := c.acquire(update)
+	if !acquired {
+		<-wait
+
+		return false
+	}
+
+	for ok := true; ok; update, ok = c.next(update.ID()) {
+		handle(update)
+	}
+
+	return true
+}
+
+func (c *coalescer) acquire(update domain.UpdateCompleted) (bool, <-chan struct{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	current, inFlight := c.flights[update.ID()]
+	if !inFlight {
+		c.flights[update.ID()] = &flight{date: update.Date(), done: make(chan struct{})}
+
+		return true, nil
+	}
+
+	if update.Date().Before(current.date) {
+		current.dropped++
+
+		return false, current.done
+	}
+
+	if current.next == nil {
+		current.next = &queued{update: update, done: make(chan struct{})}
+	} else if !update.Date().Before(current.next.update.Date()) {
+		current.next.update = update
+	}
+
+	current.next.merged++
+
+	return false, current.next.done
+}
+
+// next завершает выполняющееся обновление и возвращает отложенный запрос или освобождает таблицу, если отложенных
+// запросов нет.
+func (c *coalescer) next(id domain.ID) (domain.UpdateCompleted, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	current := c.flights[id]
+	close(current.done)
+
+	if current.next == nil {
+		delete(c.flights, id)
+
+		return domain.UpdateCompleted{}, false
+	}
+
+	c.flights[id] = &flight{date: current.next.update.Date(), done: current.next.done}
+
+	return current.next.update, true
+}
@@ -0,0 +1,96 @@
+package template
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoalescer(t *testing.T) {
+	day := func(n int) time.Time {
+		return time.Date(2022, 1, n, 0, 0, 0, 0, time.UTC)
+	}
+
+	tbl := []struct {
+		inFlight int
+		requests []int
+		handled  []time.Time
+	}{
+		{1, []int{3, 2, 3}, []time.Time{day(1), day(3)}},
+		{2, []int{1, 2, 3}, []time.Time{day(2), day(3)}},
+		{3, []int{1, 2, 3}, []time.Time{day(3), day(3)}},
+		{3, []int{1, 2}, []time.Time{day(3)}},
+	}
+
+	for _, testCase := range tbl {
+		coalescer := newCoalescer()
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+		done := make(chan struct{})
+
+		var handled []time.Time
+
+		go func() {
+			defer close(done)
+
+			coalescer.run(domain.NewUpdateCompleted(_testID, day(testCase.inFlight)), func(update domain.UpdateCompleted) {
+				if len(handled) == 0 {
+					close(started)
+					<-release
+				}
+
+				handled = append(handled, update.Date())
+			})
+		}()
+
+		<-started
+
+		coalesced := make(chan bool)
+
+		for _, n := range testCase.requests {
+			update := domain.NewUpdateCompleted(_testID, day(n))
+
+			go func() {
+				coalesced <- !coalescer.run(update, nil)
+			}()
+		}
+
+		waitQueued(t, coalescer, len(testCase.requests))
+		close(release)
+		<-done
+
+		for range testCase.requests {
+			assert.True(t, <-coalesced, "Запрос не объединен с выполняющимся обновлением")
+		}
+
+		assert.Equal(t, testCase.handled, handled, "Некорректные объединенные обновления")
+
+		ok := coalescer.run(domain.NewUpdateCompleted(_testID, day(4)), func(update domain.UpdateCompleted) {})
+		assert.True(t, ok, "Таблица не освобождена после обновления")
+	}
+}
+
+// waitQueued дожидается, пока заданное количество запросов будет объединено с выполняющимся обновлением.
+func waitQueued(t *testing.T, c *coalescer, count int) {
+	t.Helper()
+
+	assert.Eventually(t, func() bool {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+
+		current := c.flights[_testID]
+		if current == nil {
+			return false
+		}
+
+		waiting := current.dropped
+		if current.next != nil {
+			waiting += current.next.merged
+		}
+
+		return waiting == count
+	}, time.Second, time.Millisecond)
+}
@@ -8,8 +8,10 @@ import (
 // EventCtxFunc - функция, создающие контексты для обработки отдельных событий.
 type EventCtxFunc func() (context.Context, context.CancelFunc)
 
-func EventCtxFuncWithTimeout(timeout time.Duration) EventCtxFunc {
+// EventCtxFuncWithTimeout создает контексты с ограничением времени, которые отменяются вместе с родительским
+// контекстом правил при аварийной остановке.
+func EventCtxFuncWithTimeout(parent context.Context, timeout time.Duration) EventCtxFunc {
 	return func() (context.Context, context.CancelFunc) {
-		return context.WithTimeout(context.Background(), timeout)
+		return context.WithTimeout(parent, timeout)
 	}
 }
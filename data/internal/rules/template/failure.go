@@ -0,0 +1,58 @@
+package template
+
+import (
+	"errors"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+)
+
+// gatewayError - ошибка загрузки данных шлюзом.
+type gatewayError struct {
+	err error
+}
+
+func (e gatewayError) Error() string {
+	return e.err.Error()
+}
+
+func (e gatewayError) Unwrap() error {
+	return e.err
+}
+
+// validationError - ошибка проверки новых данных валидатором.
+type validationError struct {
+	err error
+}
+
+func (e validationError) Error() string {
+	return e.err.Error()
+}
+
+func (e validationError) Unwrap() error {
+	return e.err
+}
+
+// validate проверяет новые строки, помечая ошибки проверки для выбора события о неудачном обновлении.
+func (r Rule[R]) validate(table domain.Table[R], rows []R) error {
+	if err := r.validator(table, rows); err != nil {
+		return validationError{err: err}
+	}
+
+	return nil
+}
+
+// failed - событие неудачного обновления таблицы в зависимости от этапа, на котором возникла ошибка.
+func failed(update domain.UpdateCompleted, err error) domain.Event {
+	var (
+		gateway    gatewayError
+		validation validationError
+	)
+
+	switch {
+	case errors.As(err, &gateway):
+		return domain.NewGatewayFailed(update, err)
+	case errors.As(err, &validation):
+		return domain.NewValidationFailed(update, err)
+	default:
+		return domain.NewErrorOccurred(update, err)
+	}
+}
@@ -3,7 +3,9 @@ package template
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"net/http"
 	"time"
 )
 
@@ -12,3 +14,15 @@ var ErrRuleGateway = errors.New("rule gateway error")
 type Gateway[R any] interface {
 	Get(ctx context.Context, table domain.Table[R], date time.Time) ([]R, error)
 }
+
+// ErrTransient - временная ошибка шлюза, после которой имеет смысл повторить обновление.
+var ErrTransient = fmt.Errorf("%w: transient", ErrRuleGateway)
+
+// StatusError формирует ошибку по неудачному статусу ответа. Ошибки на стороне сервера считаются временными.
+func StatusError(resp *http.Response) error {
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("%w: bad respond status %s", ErrTransient, resp.Status)
+	}
+
+	return fmt.Errorf("%w: bad respond status %s", ErrRuleGateway, resp.Status)
+}
@@ -0,0 +1,38 @@
+package template
+
+import (
+	"fmt"
+	"github.com/WLM1ke/gomoex"
+	"net/http"
+)
+
+// NewISSClient создает клиент MOEX ISS, отличающий ошибки сервера от остальных ответов с неуспешным статусом.
+//
+// Клиент ISS сообщает о любом неуспешном статусе одинаково и не сохраняет исходную ошибку, поэтому ответы сервера с
+// ошибкой 5xx преобразуются в ошибку выполнения запроса, которая считается временной.
+func NewISSClient(client *http.Client) *gomoex.ISSClient {
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	issClient := *client
+	issClient.Transport = issTransport{base: base}
+
+	return gomoex.NewISSClient(&issClient)
+}
+
+type issTransport struct {
+	base http.RoundTripper
+}
+
+func (t issTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode < http.StatusInternalServerError {
+		return resp, err
+	}
+
+	_ = resp.Body.Close()
+
+	return nil, fmt.Errorf("%w: server status %s", ErrTransient, resp.Status)
+}
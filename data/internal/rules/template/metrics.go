@@ -0,0 +1,36 @@
+package template
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"time"
+)
+
+var (
+	ruleDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "data_rule_update_duration_seconds",
+			Help: "Duration of table updates by rules including retries.",
+		},
+		[]string{"rule", "result"},
+	)
+	gatewayDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "data_gateway_duration_seconds",
+			Help: "Duration of gateway calls by data source.",
+		},
+		[]string{"source"},
+	)
+	gatewayErrors = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "data_gateway_errors_total",
+			Help: "Number of failed gateway calls by data source.",
+		},
+		[]string{"source"},
+	)
+)
+
+// observeRule учитывает длительность обновления таблицы правилом с заданным результатом.
+func observeRule(start time.Time, rule, result string) {
+	ruleDuration.WithLabelValues(rule, result).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,87 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const _concurrencySeparator = ":"
+
+// ErrConcurrency - некорректное описание ограничения количества одновременных обновлений.
+var ErrConcurrency = errors.New("bad concurrency limit")
+
+// Options - общие настройки правил обновления таблиц.
+type Options struct {
+	// Context - контекст правил, отменяемый при аварийной остановке. Nil означает контекст без отмены.
+	Context context.Context
+	// Timeout - ограничение времени на одну попытку обновления таблицы.
+	Timeout time.Duration
+	// Retry - настройки повторных попыток при временных ошибках.
+	Retry Retry
+	// DeadLetters - хранилище неудачных обновлений.
+	DeadLetters repo.DeadLetterWriter
+	// Concurrency - максимальное количество одновременно обновляемых правилом таблиц. Ноль снимает ограничение.
+	Concurrency int
+	// RuleConcurrency - ограничения количества одновременно обновляемых таблиц для отдельных правил по их названиям.
+	RuleConcurrency map[string]int
+	// Breakers - общие для всех правил предохранители внешних источников данных. Nil отключает предохранители.
+	Breakers *Breakers
+}
+
+// context - контекст правил.
+func (o Options) context() context.Context {
+	if o.Context == nil {
+		return context.Background()
+	}
+
+	return o.Context
+}
+
+// concurrency - ограничение количества одновременно обновляемых таблиц для правила.
+func (o Options) concurrency(rule string) int {
+	if limit, ok := o.RuleConcurrency[rule]; ok {
+		return limit
+	}
+
+	return o.Concurrency
+}
+
+// ParseConcurrency разбирает ограничения для отдельных правил в формате Rule:limit.
+func ParseConcurrency(list []string) (map[string]int, error) {
+	limits := make(map[string]int, len(list))
+
+	for _, raw := range list {
+		if raw == "" {
+			continue
+		}
+
+		parts := strings.Split(raw, _concurrencySeparator)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%w: %s", ErrConcurrency, raw)
+		}
+
+		limit, err := strconv.Atoi(parts[1])
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("%w: %s", ErrConcurrency, raw)
+		}
+
+		limits[parts[0]] = limit
+	}
+
+	return limits, nil
+}
+
+// Retry - настройки повторных попыток обновления таблиц.
+//
+// Задержка перед каждой следующей попыткой удваивается.
+type Retry struct {
+	// Attempts - общее количество попыток, включая первую.
+	Attempts int
+	// Delay - задержка перед первой повторной попыткой.
+	Delay time.Duration
+}
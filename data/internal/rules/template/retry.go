@@ -0,0 +1,51 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"github.com/WLM1ke/gomoex"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"net"
+	"strings"
+)
+
+// Описания ошибок клиента ISS, возникающих при обращении к серверу. Остальные ошибки клиента - ответы с неуспешным
+// статусом и ошибки разбора данных - считаются постоянными.
+var _issTransient = []string{
+	"can't make request",
+	"can't read request",
+}
+
+// isTransient проверяет, может ли повторная попытка обновления завершиться успешно.
+//
+// Временными считаются сетевые ошибки, ошибки сервера, превышение времени ожидания и ошибки хранилища. Ошибки
+// валидации, разбора данных и ответы с ошибкой клиента 4xx считаются постоянными.
+func isTransient(err error) bool {
+	var netErr net.Error
+
+	switch {
+	case errors.Is(err, ErrNewRowsValidation):
+		return false
+	case errors.Is(err, ErrTransient),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, repo.ErrInternal),
+		errors.Is(err, repo.ErrTableUpdate),
+		errors.As(err, &netErr):
+		return true
+	case errors.Is(err, gomoex.ErrISSClient):
+		return isTransientISS(err)
+	default:
+		return false
+	}
+}
+
+// isTransientISS определяет временные ошибки клиента ISS по их описанию, так как клиент не сохраняет исходную ошибку.
+func isTransientISS(err error) bool {
+	for _, msg := range _issTransient {
+		if strings.Contains(err.Error(), msg) {
+			return true
+		}
+	}
+
+	return false
+}
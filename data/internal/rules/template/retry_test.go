@@ -0,0 +1,56 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/WLM1ke/gomoex"
+	"github.com/stretchr/testify/assert"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func fakeISS(status int, body string, err error) *gomoex.ISSClient {
+	transport := func(req *http.Request) (*http.Response, error) {
+		if err != nil {
+			return nil, err
+		}
+
+		return &http.Response{
+			StatusCode: status,
+			Status:     http.StatusText(status),
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	}
+
+	return NewISSClient(&http.Client{Transport: roundTripFunc(transport)})
+}
+
+func TestIsTransientISS(t *testing.T) {
+	tbl := []struct {
+		name      string
+		iss       *gomoex.ISSClient
+		transient bool
+	}{
+		{"network", fakeISS(0, "", errors.New("connection reset")), true},
+		{"server", fakeISS(http.StatusBadGateway, "", nil), true},
+		{"client", fakeISS(http.StatusNotFound, "", nil), false},
+		{"parse", fakeISS(http.StatusOK, "not json", nil), false},
+	}
+
+	for _, testCase := range tbl {
+		_, err := testCase.iss.MarketDates(context.Background(), gomoex.EngineStock, gomoex.MarketShares)
+
+		assert.ErrorIs(t, err, gomoex.ErrISSClient, testCase.name)
+		assert.Equal(t, testCase.transient, isTransient(err), testCase.name)
+	}
+}
@@ -2,10 +2,14 @@ package template
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/WLM1ke/poptimizer/data/internal/domain"
 	"github.com/WLM1ke/poptimizer/data/internal/repo"
 	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"github.com/prometheus/client_golang/prometheus"
 	"sync"
+	"time"
 )
 
 type Rule[R any] struct {
@@ -16,7 +20,11 @@ type Rule[R any] struct {
 	gateway   Gateway[R]
 	validator Validator[R]
 	append    bool
+	opts      Options
 	ctxFunc   EventCtxFunc
+	coalescer *coalescer
+	// limit ограничивает количество одновременных обращений к шлюзу, если задано ограничение
+	limit chan struct{}
 }
 
 func NewRule[R any](
@@ -27,8 +35,13 @@ func NewRule[R any](
 	gateway Gateway[R],
 	validator Validator[R],
 	append bool,
-	ctxFunc EventCtxFunc,
+	opts Options,
 ) Rule[R] {
+	var limit chan struct{}
+	if concurrency := opts.concurrency(name); concurrency > 0 {
+		limit = make(chan struct{}, concurrency)
+	}
+
 	return Rule[R]{
 		name:      name,
 		logger:    logger,
@@ -37,11 +50,37 @@ func NewRule[R any](
 		gateway:   gateway,
 		validator: validator,
 		append:    append,
-		ctxFunc:   ctxFunc,
+		opts:      opts,
+		ctxFunc:   EventCtxFuncWithTimeout(opts.context(), opts.Timeout),
+		coalescer: newCoalescer(),
+		limit:     limit,
 	}
 }
 
+// Subscriptions - события, на которые реагирует селектор правила, и запросы на повторное обновление таблиц.
+func (r Rule[R]) Subscriptions() []domain.Subscription {
+	return append(r.selector.Subscriptions(), domain.SubscribeAll[domain.RetryRequested]())
+}
+
+// Name - название правила.
+func (r Rule[R]) Name() string {
+	return r.name
+}
+
+// Produces - таблицы, которые может обновить правило.
+func (r Rule[R]) Produces() []domain.ID {
+	return r.selector.Produces()
+}
+
 func (r Rule[R]) Activate(in <-chan domain.Event, out chan<- domain.Event) {
+	r.ActivateWithAck(in, out, func(domain.Event) {})
+}
+
+// ActivateWithAck обрабатывает события параллельно и подтверждает обработку события после завершения всех вызванных
+// им обновлений, в том числе объединенных с выполняющимися.
+//
+// После отмены контекста правил ожидание повторных попыток и очереди на обновление прерывается.
+func (r Rule[R]) ActivateWithAck(in <-chan domain.Event, out chan<- domain.Event, ack func(domain.Event)) {
 	r.logger.Infof("%s: started", r.name)
 	defer r.logger.Infof("%s: stopped", r.name)
 
@@ -57,9 +96,13 @@ func (r Rule[R]) Activate(in <-chan domain.Event, out chan<- domain.Event) {
 			defer wg.Done()
 
 			r.handleEvent(out, event)
+
+			// Прерванные остановкой события не подтверждаются и будут повторно обработаны после перезапуска
+			if r.opts.context().Err() == nil {
+				ack(event)
+			}
 		}()
 	}
-
 }
 
 func (r Rule[R]) handleEvent(out chan<- domain.Event, event domain.Event) {
@@ -69,7 +112,7 @@ func (r Rule[R]) handleEvent(out chan<- domain.Event, event domain.Event) {
 	var wg sync.WaitGroup
 	defer wg.Wait()
 
-	ids, err := r.selector.Select(ctx, event)
+	ids, err := r.selectIDs(ctx, event)
 	if err != nil {
 		out <- domain.NewErrorOccurred(event, err)
 
@@ -79,50 +122,321 @@ func (r Rule[R]) handleEvent(out chan<- domain.Event, event domain.Event) {
 	for _, id := range ids {
 		wg.Add(1)
 
-		update := domain.NewUpdateCompleted(id, event.Date())
+		update := domain.CausedBy(event, domain.NewUpdateCompleted(id, event.Date()))
 
 		go func() {
 			defer wg.Done()
 
-			if newEvent := r.handleUpdate(ctx, update); newEvent != nil {
-				out <- newEvent
-			}
+			coalesced := !r.coalescer.run(update, func(update domain.UpdateCompleted) {
+				out <- domain.NewUpdateStarted(update, r.name)
 
+				for _, newEvent := range r.handleUpdateWithRetry(update) {
+					out <- newEvent
+				}
+			})
+
+			if coalesced {
+				r.logger.Infof("%s: %s %s coalesced with update in progress", r.name, update, update.Trace())
+			}
 		}()
 	}
 }
 
-func (r Rule[R]) handleUpdate(ctx context.Context, update domain.UpdateCompleted) domain.Event {
+// selectIDs выбирает таблицы для обновления. Запрос на повторное обновление адресован правилу по названию.
+func (r Rule[R]) selectIDs(ctx context.Context, event domain.Event) ([]domain.ID, error) {
+	if retry, ok := event.(domain.RetryRequested); ok {
+		if retry.Rule() != r.name {
+			return nil, nil
+		}
+
+		return []domain.ID{retry.ID()}, nil
+	}
+
+	return r.selector.Select(ctx, event)
+}
+
+// handleUpdateWithRetry обновляет таблицу, повторяя попытки при временных ошибках с удваивающейся задержкой.
+//
+// Неудачное обновление завершается событием, соответствующим этапу, на котором возникла ошибка, и сохраняется в
+// хранилище неудачных обновлений для ручного перезапуска, в том числе при постоянной ошибке без повторных попыток.
+// О недоступности источника сообщается однократно при размыкании его предохранителя, поэтому пропущенные обновления
+// не порождают событий-ошибок, а повторяются после восстановления источника.
+// Обновление, прерванное отменой контекста правил, завершается без событий об ошибке.
+func (r Rule[R]) handleUpdateWithRetry(update domain.UpdateCompleted) (events []domain.Event) {
+	start := time.Now()
+	delay := r.opts.Retry.Delay
+
+	for attempt := 1; ; attempt++ {
+		attemptEvents, err := r.handleUpdateLimited(update)
+		events = append(events, attemptEvents...)
+
+		switch {
+		case err == nil:
+			r.deleteDeadLetter(update)
+			observeRule(start, r.name, "ok")
+
+			return events
+		case r.opts.context().Err() != nil:
+			r.logger.Warnf("%s: %s %s interrupted -> %s", r.name, update, update.Trace(), err)
+			observeRule(start, r.name, "interrupted")
+
+			return events
+		case errors.Is(err, ErrSourceUnavailable):
+			r.logger.Infof("%s: %s %s skipped -> %s", r.name, update, update.Trace(), err)
+			r.saveDeadLetter(update, err, attempt)
+			observeRule(start, r.name, "skipped")
+
+			return events
+		case !isTransient(err):
+			r.saveDeadLetter(update, err, attempt)
+			observeRule(start, r.name, "error")
+
+			return append(events, failed(update, err))
+		case attempt >= r.opts.Retry.Attempts:
+			r.saveDeadLetter(update, err, attempt)
+			observeRule(start, r.name, "error")
+
+			return append(events, failed(update, err))
+		}
+
+		r.logger.Warnf(
+			"%s: attempt %d failed for %s %s, retrying in %s -> %s",
+			r.name,
+			attempt,
+			update,
+			update.Trace(),
+			delay,
+			err,
+		)
+
+		if !r.wait(delay) {
+			r.logger.Warnf("%s: %s %s interrupted", r.name, update, update.Trace())
+			observeRule(start, r.name, "interrupted")
+
+			return events
+		}
+
+		delay *= 2
+	}
+}
+
+// wait ожидает перед повторной попыткой и возвращает false, если контекст правил отменен.
+func (r Rule[R]) wait(delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-r.opts.context().Done():
+		return false
+	}
+}
+
+// handleUpdateLimited выполняет одну попытку обновления с учетом ограничения на количество одновременных обновлений.
+//
+// Время ожидания своей очереди не учитывается в ограничении времени на попытку обновления.
+func (r Rule[R]) handleUpdateLimited(update domain.UpdateCompleted) ([]domain.Event, error) {
+	if r.limit != nil {
+		select {
+		case r.limit <- struct{}{}:
+			defer func() { <-r.limit }()
+		case <-r.opts.context().Done():
+			return nil, r.opts.context().Err()
+		}
+	}
+
+	ctx, cancel := r.ctxFunc()
+	defer cancel()
+
+	return r.handleUpdate(ctx, update)
+}
+
+func (r Rule[R]) saveDeadLetter(update domain.UpdateCompleted, err error, attempts int) {
+	if r.opts.DeadLetters == nil {
+		return
+	}
+
+	ctx, cancel := r.ctxFunc()
+	defer cancel()
+
+	retries := 0
+	if isTransient(err) || errors.Is(err, ErrSourceUnavailable) {
+		retries = attempts - 1
+	}
+
+	letter := repo.DeadLetter{
+		Group:       string(update.Group()),
+		Name:        string(update.Name()),
+		Date:        update.Date(),
+		Rule:        r.name,
+		Error:       err.Error(),
+		Attempts:    attempts,
+		Retries:     retries,
+		Created:     time.Now(),
+		Event:       update.Trace().ID,
+		Correlation: update.Trace().Correlation,
+		Source:      r.source(),
+		Skipped:     errors.Is(err, ErrSourceUnavailable),
+	}
+
+	if err := r.opts.DeadLetters.Save(ctx, letter); err != nil {
+		r.logger.Warnf("%s: can't save dead letter for %s -> %s", r.name, update, err)
+	}
+}
+
+func (r Rule[R]) deleteDeadLetter(update domain.UpdateCompleted) {
+	if r.opts.DeadLetters == nil {
+		return
+	}
+
+	ctx, cancel := r.ctxFunc()
+	defer cancel()
+
+	if err := r.opts.DeadLetters.Delete(ctx, update.ID()); err != nil {
+		r.logger.Warnf("%s: can't delete dead letter for %s -> %s", r.name, update, err)
+	}
+}
+
+// handleUpdate выполняет одну попытку обновления.
+//
+// Наряду с ошибкой может возвращать события об изменении доступности источника данных. Если новых данных нет,
+// сообщает о пропуске обновления.
+func (r Rule[R]) handleUpdate(ctx context.Context, update domain.UpdateCompleted) ([]domain.Event, error) {
 	table, err := r.repo.Get(ctx, update.ID())
 	if err != nil {
-		return domain.NewErrorOccurred(update, err)
+		return nil, err
 	}
 
-	rows, err := r.gateway.Get(ctx, table, update.Date())
+	rows, notices, err := r.getRows(ctx, update, table)
 	if err != nil {
-		return domain.NewErrorOccurred(update, err)
+		return notices, err
 	}
 
 	if !r.haveNewRows(rows) {
-		return nil
+		return append(notices, domain.NewUpdateSkipped(update)), nil
 	}
 
-	err = r.validator(table, rows)
-	if err != nil {
-		return domain.NewErrorOccurred(update, err)
+	err = r.validate(table, rows)
+
+	switch {
+	case r.append && errors.Is(err, ErrHistoryRevision):
+		events, err := r.handleRevision(ctx, update, err)
+
+		return append(notices, events...), err
+	case err != nil:
+		return notices, err
 	}
 
 	if r.append {
-		err = r.repo.Append(ctx, domain.NewTable(update.ID(), update.Date(), rows[1:]))
+		err = r.repo.Append(ctx, domain.NewTable(update.ID(), update.Date(), rows[1:]).WithSource(r.source()))
 	} else {
-		err = r.repo.Replace(ctx, domain.NewTable(update.ID(), update.Date(), rows))
+		err = r.repo.Replace(ctx, domain.NewTable(update.ID(), update.Date(), rows).WithSource(r.source()))
+	}
+
+	if err != nil {
+		return notices, err
+	}
+
+	return append(notices, update), nil
+}
+
+// handleRevision полностью перезагружает таблицу, если источник пересмотрел уже сохраненные данные.
+func (r Rule[R]) handleRevision(
+	ctx context.Context,
+	update domain.UpdateCompleted,
+	reason error,
+) ([]domain.Event, error) {
+	r.logger.Warnf("%s: reloading %s %s -> %s", r.name, update, update.Trace(), reason)
+
+	empty := domain.NewEmptyTable[R](update.ID())
+
+	rows, notices, err := r.getRows(ctx, update, empty)
+	if err != nil {
+		return notices, err
 	}
 
+	if len(rows) == 0 {
+		return notices, fmt.Errorf("%w: no rows after reload", reason)
+	}
+
+	if err = r.validate(empty, rows); err != nil {
+		return notices, err
+	}
+
+	if err = r.repo.Replace(ctx, domain.NewTable(update.ID(), update.Date(), rows).WithSource(r.source())); err != nil {
+		return notices, err
+	}
+
+	return append(notices, domain.NewRevisionDetected(update, reason), update), nil
+}
+
+// getRows загружает строки из шлюза, учитывая длительность и ошибки обращения к источнику данных.
+//
+// Обращения к внешнему источнику проходят через его предохранитель, изменение состояния которого порождает
+// события о недоступности или восстановлении источника.
+func (r Rule[R]) getRows(
+	ctx context.Context,
+	update domain.UpdateCompleted,
+	table domain.Table[R],
+) (rows []R, notices []domain.Event, err error) {
+	source, sourced := r.gateway.(Sourced)
+	if !sourced || r.opts.Breakers == nil {
+		rows, err = r.callGateway(ctx, table, update.Date())
+
+		return rows, nil, err
+	}
+
+	if err = r.opts.Breakers.allow(ctx, source.Source()); err != nil {
+		return nil, nil, err
+	}
+
+	rows, err = r.callGateway(ctx, table, update.Date())
+
+	switch {
+	case isInterrupted(ctx, err), errors.Is(err, repo.ErrInternal), errors.Is(err, repo.ErrTableUpdate):
+		// Прерывание обновления при остановке и ошибки хранилища, используемого шлюзом, не относятся к источнику
+		r.opts.Breakers.release(source.Source())
+	case isTransient(err):
+		if r.opts.Breakers.failure(source.Source()) {
+			r.logger.Warnf("%s: source %s unavailable -> %s", r.name, source.Source(), err)
+			notices = append(notices, domain.NewSourceUnavailable(update, source.Source(), err))
+		}
+	// Постоянные ошибки, например, разбора данных, означают, что источник доступен
+	case r.opts.Breakers.success(source.Source()):
+		r.logger.Infof("%s: source %s recovered", r.name, source.Source())
+		notices = append(notices, domain.NewSourceRecovered(update, source.Source()))
+	}
+
+	return rows, notices, err
+}
+
+// isInterrupted проверяет, прервано ли обращение к шлюзу отменой контекста правил при остановке.
+func isInterrupted(ctx context.Context, err error) bool {
+	return errors.Is(ctx.Err(), context.Canceled) || errors.Is(err, context.Canceled)
+}
+
+// source - источник данных шлюза, а для шлюзов, рассчитывающих данные по другим таблицам, - название правила.
+func (r Rule[R]) source() string {
+	if source, ok := r.gateway.(Sourced); ok {
+		return source.Source()
+	}
+
+	return r.name
+}
+
+// callGateway загружает строки из шлюза, учитывая длительность и ошибки обращений по источникам данных.
+func (r Rule[R]) callGateway(ctx context.Context, table domain.Table[R], date time.Time) ([]R, error) {
+	defer prometheus.NewTimer(gatewayDuration.WithLabelValues(r.source())).ObserveDuration()
+
+	rows, err := r.gateway.Get(ctx, table, date)
 	if err != nil {
-		return domain.NewErrorOccurred(update, err)
+		gatewayErrors.WithLabelValues(r.source()).Inc()
+
+		return nil, gatewayError{err: err}
 	}
 
-	return update
+	return rows, nil
 }
 
 func (r Rule[R]) haveNewRows(rows []R) bool {
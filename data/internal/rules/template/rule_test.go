@@ -0,0 +1,374 @@
+package template
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+var _testID = domain.NewID("test", "test")
+
+type fakeRepo struct {
+	table domain.Table[int]
+}
+
+func (f *fakeRepo) Get(_ context.Context, _ domain.ID) (domain.Table[int], error) {
+	return f.table, nil
+}
+
+func (f *fakeRepo) Replace(_ context.Context, table domain.Table[int]) error {
+	f.table = table
+
+	return nil
+}
+
+func (f *fakeRepo) Append(_ context.Context, table domain.Table[int]) error {
+	f.table = domain.NewTable(table.ID(), table.Date(), append(f.table.Rows(), table.Rows()...))
+
+	return nil
+}
+
+// fakeGateway возвращает полную историю или ее хвост, начиная с последней сохраненной строки.
+type fakeGateway struct {
+	history []int
+}
+
+func (f fakeGateway) Get(_ context.Context, table domain.Table[int], _ time.Time) ([]int, error) {
+	if table.IsEmpty() {
+		return f.history, nil
+	}
+
+	return f.history[len(table.Rows())-1:], nil
+}
+
+func fakeValidator(table domain.Table[int], rows []int) error {
+	if !table.IsEmpty() && table.LastRow() != rows[0] {
+		return fmt.Errorf("%w: %d vs %d", ErrHistoryRevision, table.LastRow(), rows[0])
+	}
+
+	return nil
+}
+
+func TestRuleHandleUpdate(t *testing.T) {
+	tbl := []struct {
+		stored []int
+		events int
+		rows   []int
+	}{
+		{[]int{1, 2}, 1, []int{1, 2, 3}},
+		{[]int{5, 10}, 2, []int{1, 2, 3}},
+	}
+
+	for _, testCase := range tbl {
+		repo := fakeRepo{table: domain.NewTable(_testID, time.Time{}, testCase.stored)}
+		rule := NewRule[int](
+			"TestRule",
+			lgr.NoOp(),
+			&repo,
+			NewSelectOnTableUpdate(_testID, _testID),
+			fakeGateway{history: []int{1, 2, 3}},
+			fakeValidator,
+			true,
+			Options{Timeout: time.Second},
+		)
+
+		events, err := rule.handleUpdate(context.Background(), domain.NewUpdateCompleted(_testID, time.Time{}))
+
+		assert.Nil(t, err, "Ошибка обновления")
+		assert.Equal(t, testCase.events, len(events), "Некорректное количество событий")
+		assert.IsType(t, domain.UpdateCompleted{}, events[len(events)-1], "Не последнее событие об обновлении")
+		assert.Equal(t, testCase.rows, repo.table.Rows(), "Некорректные строки таблицы")
+	}
+}
+
+// flakyGateway возвращает заданную ошибку, пока не исчерпает количество сбоев.
+type flakyGateway struct {
+	failures int
+	calls    int
+	err      error
+}
+
+func (f *flakyGateway) Get(_ context.Context, _ domain.Table[int], _ time.Time) ([]int, error) {
+	f.calls++
+
+	if f.calls <= f.failures {
+		return nil, f.err
+	}
+
+	return []int{1}, nil
+}
+
+type fakeDeadLetters struct {
+	letters map[domain.ID]repo.DeadLetter
+}
+
+func (f *fakeDeadLetters) Save(_ context.Context, letter repo.DeadLetter) error {
+	f.letters[letter.ID()] = letter
+
+	return nil
+}
+
+func (f *fakeDeadLetters) Delete(_ context.Context, id domain.ID) error {
+	delete(f.letters, id)
+
+	return nil
+}
+
+func TestRuleHandleUpdateWithRetry(t *testing.T) {
+	tbl := []struct {
+		failures int
+		err      error
+		calls    int
+		letters  int
+		retries  int
+		last     domain.Event
+	}{
+		{2, ErrTransient, 3, 0, 0, domain.UpdateCompleted{}},
+		{3, ErrTransient, 3, 1, 2, domain.GatewayFailed{}},
+		{3, ErrRuleGateway, 1, 1, 0, domain.GatewayFailed{}},
+	}
+
+	for _, testCase := range tbl {
+		gateway := flakyGateway{failures: testCase.failures, err: testCase.err}
+		letters := fakeDeadLetters{letters: make(map[domain.ID]repo.DeadLetter)}
+		rule := NewRule[int](
+			"TestRule",
+			lgr.NoOp(),
+			&fakeRepo{table: domain.NewEmptyTable[int](_testID)},
+			NewSelectOnTableUpdate(_testID, _testID),
+			&gateway,
+			fakeValidator,
+			false,
+			Options{
+				Timeout:     time.Second,
+				Retry:       Retry{Attempts: 3, Delay: time.Millisecond},
+				DeadLetters: &letters,
+			},
+		)
+
+		events := rule.handleUpdateWithRetry(domain.NewUpdateCompleted(_testID, time.Time{}))
+
+		assert.Equal(t, testCase.calls, gateway.calls, "Некорректное количество попыток")
+		assert.Equal(t, testCase.letters, len(letters.letters), "Некорректное количество неудачных обновлений")
+		assert.Equal(t, testCase.retries, letters.letters[_testID].Retries, "Некорректное количество повторных попыток")
+		assert.IsType(t, testCase.last, events[len(events)-1], "Некорректное последнее событие")
+	}
+}
+
+func TestRuleHandleUpdateLifecycle(t *testing.T) {
+	failing := func(_ domain.Table[int], _ []int) error {
+		return fmt.Errorf("%w: bad rows", ErrNewRowsValidation)
+	}
+
+	tbl := []struct {
+		stored    []int
+		validator Validator[int]
+		last      domain.Event
+	}{
+		{[]int{1, 2, 3}, fakeValidator, domain.UpdateSkipped{}},
+		{[]int{1, 2}, failing, domain.ValidationFailed{}},
+	}
+
+	for _, testCase := range tbl {
+		rule := NewRule[int](
+			"TestRule",
+			lgr.NoOp(),
+			&fakeRepo{table: domain.NewTable(_testID, time.Time{}, testCase.stored)},
+			NewSelectOnTableUpdate(_testID, _testID),
+			fakeGateway{history: []int{1, 2, 3}},
+			testCase.validator,
+			true,
+			Options{Timeout: time.Second},
+		)
+
+		events := rule.handleUpdateWithRetry(domain.NewUpdateCompleted(_testID, time.Time{}))
+
+		assert.Equal(t, 1, len(events), "Некорректное количество событий")
+		assert.IsType(t, testCase.last, events[len(events)-1], "Некорректное последнее событие")
+	}
+}
+
+func TestRuleSavesPermanentFailure(t *testing.T) {
+	failing := func(_ domain.Table[int], _ []int) error {
+		return fmt.Errorf("%w: bad rows", ErrNewRowsValidation)
+	}
+
+	letters := fakeDeadLetters{letters: make(map[domain.ID]repo.DeadLetter)}
+	rule := NewRule[int](
+		"TestRule",
+		lgr.NoOp(),
+		&fakeRepo{table: domain.NewTable(_testID, time.Time{}, []int{1, 2})},
+		NewSelectOnTableUpdate(_testID, _testID),
+		fakeGateway{history: []int{1, 2, 3}},
+		failing,
+		true,
+		Options{
+			Timeout:     time.Second,
+			Retry:       Retry{Attempts: 3, Delay: time.Millisecond},
+			DeadLetters: &letters,
+		},
+	)
+
+	events := rule.handleUpdateWithRetry(domain.NewUpdateCompleted(_testID, time.Time{}))
+	letter, ok := letters.letters[_testID]
+
+	assert.IsType(t, domain.ValidationFailed{}, events[len(events)-1], "Некорректное последнее событие")
+	assert.True(t, ok, "Обновление с постоянной ошибкой не сохранено")
+	assert.Equal(t, 1, letter.Attempts, "Повторные попытки при постоянной ошибке")
+	assert.Equal(t, 0, letter.Retries, "Повторные попытки при постоянной ошибке")
+	assert.Contains(t, letter.Error, "bad rows", "Не сохранена ошибка обновления")
+}
+
+func TestRuleHandleUpdateWithRetryInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	gateway := flakyGateway{failures: 3, err: ErrTransient}
+	letters := fakeDeadLetters{letters: make(map[domain.ID]repo.DeadLetter)}
+	rule := NewRule[int](
+		"TestRule",
+		lgr.NoOp(),
+		&fakeRepo{table: domain.NewEmptyTable[int](_testID)},
+		NewSelectOnTableUpdate(_testID, _testID),
+		&gateway,
+		fakeValidator,
+		false,
+		Options{
+			Context:     ctx,
+			Timeout:     time.Second,
+			Retry:       Retry{Attempts: 3, Delay: time.Hour},
+			DeadLetters: &letters,
+			Concurrency: 1,
+		},
+	)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	events := rule.handleUpdateWithRetry(domain.NewUpdateCompleted(_testID, time.Time{}))
+
+	assert.Equal(t, 1, gateway.calls, "Повторная попытка после остановки")
+	assert.Empty(t, events, "События об ошибке после остановки")
+	assert.Empty(t, letters.letters, "Неудачное обновление после остановки")
+
+	rule.limit <- struct{}{}
+	_, err := rule.handleUpdateLimited(domain.NewUpdateCompleted(_testID, time.Time{}))
+
+	assert.ErrorIs(t, err, context.Canceled, "Ожидание очереди после остановки")
+}
+
+// sourcedGateway - шлюз внешнего источника данных.
+type sourcedGateway struct {
+	flakyGateway
+}
+
+func (s *sourcedGateway) Source() string {
+	return "test_source"
+}
+
+func TestRuleGatewayMetricsBySource(t *testing.T) {
+	gateway := sourcedGateway{flakyGateway: flakyGateway{failures: 1, err: ErrRuleGateway}}
+	rule := NewRule[int](
+		"SourcedRule",
+		lgr.NoOp(),
+		&fakeRepo{table: domain.NewEmptyTable[int](_testID)},
+		NewSelectOnTableUpdate(_testID, _testID),
+		&gateway,
+		fakeValidator,
+		false,
+		Options{Timeout: time.Second},
+	)
+
+	before := testutil.ToFloat64(gatewayErrors.WithLabelValues("test_source"))
+
+	rule.handleUpdateWithRetry(domain.NewUpdateCompleted(_testID, time.Time{}))
+
+	assert.Equal(t, before+1, testutil.ToFloat64(gatewayErrors.WithLabelValues("test_source")))
+	assert.Zero(t, testutil.ToFloat64(gatewayErrors.WithLabelValues("SourcedRule")))
+}
+
+func TestRuleGetRowsIgnoresInterrupted(t *testing.T) {
+	breakers := NewBreakers(1, time.Hour)
+	gateway := sourcedGateway{flakyGateway: flakyGateway{failures: 1, err: context.Canceled}}
+	rule := NewRule[int](
+		"SourcedRule",
+		lgr.NoOp(),
+		&fakeRepo{table: domain.NewEmptyTable[int](_testID)},
+		NewSelectOnTableUpdate(_testID, _testID),
+		&gateway,
+		fakeValidator,
+		false,
+		Options{Timeout: time.Second, Breakers: breakers},
+	)
+
+	update := domain.NewUpdateCompleted(_testID, time.Time{})
+	_, notices, err := rule.getRows(context.Background(), update, domain.NewEmptyTable[int](_testID))
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, notices, "Прерванное обращение изменило состояние источника")
+	assert.Nil(t, breakers.allow(context.Background(), gateway.Source()), "Прерванное обращение учтено как сбой")
+}
+
+// blockingGateway задерживает первое обращение до сигнала release.
+type blockingGateway struct {
+	started chan struct{}
+	release chan struct{}
+	calls   int32
+}
+
+func (b *blockingGateway) Get(_ context.Context, _ domain.Table[int], _ time.Time) ([]int, error) {
+	if atomic.AddInt32(&b.calls, 1) == 1 {
+		close(b.started)
+		<-b.release
+	}
+
+	return []int{1}, nil
+}
+
+func TestRuleHandleEventRepeatsSameDateTrigger(t *testing.T) {
+	gateway := blockingGateway{started: make(chan struct{}), release: make(chan struct{})}
+	rule := NewRule[int](
+		"TestRule",
+		lgr.NoOp(),
+		&fakeRepo{table: domain.NewEmptyTable[int](_testID)},
+		NewSelectOnTableUpdate(_testID, _testID),
+		&gateway,
+		func(_ domain.Table[int], _ []int) error { return nil },
+		false,
+		Options{Timeout: time.Second},
+	)
+
+	date := time.Date(2022, time.January, 10, 0, 0, 0, 0, time.UTC)
+	out := make(chan domain.Event, 10)
+
+	var wg sync.WaitGroup
+
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		rule.handleEvent(out, domain.NewUpdateCompleted(_testID, date))
+	}()
+
+	<-gateway.started
+
+	go func() {
+		defer wg.Done()
+		rule.handleEvent(out, domain.NewUpdateCompleted(_testID, date))
+	}()
+
+	waitQueued(t, rule.coalescer, 1)
+	close(gateway.release)
+	wg.Wait()
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&gateway.calls), "Запрос с той же датой не обновил таблицу повторно")
+}
@@ -5,8 +5,14 @@ import (
 	"github.com/WLM1ke/poptimizer/data/internal/domain"
 )
 
+// Selector выбирает таблицы, которые необходимо обновить в результате события.
 type Selector interface {
+	// Subscriptions - события, которые могут привести к обновлению таблиц.
+	Subscriptions() []domain.Subscription
+	// Select выбирает таблицы для обновления.
 	Select(ctx context.Context, event domain.Event) ([]domain.ID, error)
+	// Produces - таблицы, которые может выбрать селектор. Пустое название таблицы означает любую таблицу группы.
+	Produces() []domain.ID
 }
 
 type SelectOnTableUpdate struct {
@@ -18,6 +24,14 @@ func NewSelectOnTableUpdate(on domain.ID, update domain.ID) SelectOnTableUpdate
 	return SelectOnTableUpdate{on: on, update: update}
 }
 
+func (s SelectOnTableUpdate) Subscriptions() []domain.Subscription {
+	return []domain.Subscription{domain.Subscribe[domain.UpdateCompleted](s.on)}
+}
+
+func (s SelectOnTableUpdate) Produces() []domain.ID {
+	return []domain.ID{s.update}
+}
+
 func (s SelectOnTableUpdate) Select(_ context.Context, event domain.Event) (ids []domain.ID, err error) {
 	switch selected := event.(type) {
 	case domain.UpdateCompleted:
@@ -5,6 +5,13 @@ import (
 	"github.com/WLM1ke/poptimizer/data/internal/domain"
 )
 
-var ErrNewRowsValidation = errors.New("new rows validation error")
+var (
+	ErrNewRowsValidation = errors.New("new rows validation error")
+	// ErrHistoryRevision - первая новая строка совпадает по дате с последней сохраненной, но отличается значениями.
+	//
+	// Обычно связано с пересмотром истории источником данных (сплиты, консолидации, редомициляция) и приводит к
+	// полной перезагрузке таблицы при дописывании строк.
+	ErrHistoryRevision = errors.New("history revision")
+)
 
 type Validator[R any] func(table domain.Table[R], rows []R) error
@@ -0,0 +1,2 @@
+// Package totalreturn содержит правило расчета индексов полной доходности отдельных бумаг.
+package totalreturn
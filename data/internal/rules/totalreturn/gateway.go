@@ -0,0 +1,108 @@
+package totalreturn
+
+import (
+	"context"
+	"fmt"
+	"github.com/WLM1ke/gomoex"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/dividends"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/quotes"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/usd"
+	"sort"
+	"time"
+)
+
+const (
+	_rub = `RUB`
+	_usd = `USD`
+)
+
+type gateway struct {
+	quotes    repo.Read[gomoex.Candle]
+	dividends repo.Read[dividends.Dividend]
+	usd       repo.Read[gomoex.Candle]
+}
+
+func (g gateway) Get(ctx context.Context, table domain.Table[TotalReturn], _ time.Time) ([]TotalReturn, error) {
+	ticker := string(table.Name())
+
+	candles, err := g.quotes.Get(ctx, quotes.ID(ticker))
+	if err != nil || candles.IsEmpty() {
+		return nil, err
+	}
+
+	divs, err := g.dividends.Get(ctx, dividends.ID(ticker))
+	if err != nil {
+		return nil, err
+	}
+
+	rates, err := g.usd.Get(ctx, usd.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	rubDivs, err := toRUB(divs.Rows(), rates.Rows())
+	if err != nil {
+		return nil, err
+	}
+
+	return totalReturn(candles.Rows(), rubDivs), nil
+}
+
+// toRUB переводит дивиденды в рубли по биржевому курсу последнего торгового дня не позже даты закрытия реестра.
+func toRUB(divs []dividends.Dividend, rates []gomoex.Candle) ([]dividends.Dividend, error) {
+	rubDivs := make([]dividends.Dividend, 0, len(divs))
+
+	for _, div := range divs {
+		switch div.Currency {
+		case _rub:
+		case _usd:
+			n := sort.Search(len(rates), func(i int) bool { return rates[i].Begin.After(div.Date) })
+			if n == 0 {
+				return nil, fmt.Errorf("%w: no usd rate for %+v", template.ErrRuleGateway, div)
+			}
+
+			div.Value *= rates[n-1].Close
+			div.Currency = _rub
+		default:
+			return nil, fmt.Errorf("%w: unknown currency %+v", template.ErrRuleGateway, div)
+		}
+
+		rubDivs = append(rubDivs, div)
+	}
+
+	return rubDivs, nil
+}
+
+// totalReturn рассчитывает индекс полной доходности.
+//
+// При расчетах в режиме T+1 последний торговый день перед датой закрытия реестра является днем отсечки, а следующий
+// за ним торговый день - первым днем торгов без дивиденда. Дивиденд прибавляется к цене закрытия этого дня при
+// расчете его доходности относительно цены закрытия дня отсечки. Дивиденды без котировок в первый день торгов без
+// дивиденда пропускаются до появления новых котировок.
+func totalReturn(candles []gomoex.Candle, divs []dividends.Dividend) []TotalReturn {
+	rows := make([]TotalReturn, 0, len(candles))
+
+	for _, candle := range candles {
+		rows = append(rows, TotalReturn{Date: candle.Begin, Close: candle.Close})
+	}
+
+	for _, div := range divs {
+		n := sort.Search(len(rows), func(i int) bool { return !rows[i].Date.Before(div.Date) })
+		if n == len(rows) || n == 0 {
+			continue
+		}
+
+		rows[n].Dividend += div.Value
+	}
+
+	rows[0].Index = rows[0].Close
+
+	for n := 1; n < len(rows); n++ {
+		rows[n].Index = rows[n-1].Index * (rows[n].Close + rows[n].Dividend) / rows[n-1].Close
+	}
+
+	return rows
+}
@@ -0,0 +1,37 @@
+package totalreturn
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WLM1ke/gomoex"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/dividends"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTotalReturn(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2022, 6, d, 0, 0, 0, 0, time.UTC) }
+
+	candles := []gomoex.Candle{
+		{Begin: day(1), Close: 100},
+		{Begin: day(2), Close: 90},
+		{Begin: day(3), Close: 99},
+		{Begin: day(6), Close: 110},
+	}
+	divs := []dividends.Dividend{
+		{Date: day(4), Value: 10, Currency: _rub},
+		{Date: day(30), Value: 5, Currency: _rub},
+	}
+
+	assert.Equal(
+		t,
+		[]TotalReturn{
+			{Date: day(1), Close: 100, Index: 100},
+			{Date: day(2), Close: 90, Index: 90},
+			{Date: day(3), Close: 99, Index: 99},
+			{Date: day(6), Close: 110, Dividend: 10, Index: 120},
+		},
+		totalReturn(candles, divs),
+		"Некорректный индекс полной доходности",
+	)
+}
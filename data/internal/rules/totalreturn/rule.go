@@ -0,0 +1,52 @@
+package totalreturn
+
+import (
+	"github.com/WLM1ke/gomoex"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/repo"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/dividends"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
+	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+const _group = "total_return"
+
+// Group - группа таблиц с индексами полной доходности отдельных бумаг.
+const Group domain.Group = _group
+
+// ID - таблица с индексом полной доходности заданной бумаги.
+func ID(ticker string) domain.ID {
+	return domain.NewID(_group, ticker)
+}
+
+// TotalReturn - цена закрытия, дивиденды в рублях, торги без которых начались в данный день, и индекс полной
+// доходности.
+//
+// Индекс полной доходности начинается с первой цены закрытия и предполагает реинвестирование дивидендов по цене
+// закрытия первого дня торгов без дивиденда, к строке которого относится дивиденд.
+type TotalReturn struct {
+	Date     time.Time
+	Close    float64
+	Dividend float64
+	Index    float64
+}
+
+// New создает правило пересчета индекса полной доходности после изменения котировок или дивидендов бумаги.
+func New(logger *lgr.Logger, db *mongo.Database, opts template.Options) domain.Rule {
+	return template.NewRule[TotalReturn](
+		"TotalReturnRule",
+		logger,
+		repo.NewMongo[TotalReturn](db),
+		selector{},
+		gateway{
+			quotes:    repo.NewMongo[gomoex.Candle](db),
+			dividends: repo.NewMongo[dividends.Dividend](db),
+			usd:       repo.NewMongo[gomoex.Candle](db),
+		},
+		validator,
+		false,
+		opts,
+	)
+}
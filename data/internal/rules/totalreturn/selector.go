@@ -0,0 +1,33 @@
+package totalreturn
+
+import (
+	"context"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/dividends"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/quotes"
+)
+
+type selector struct {
+}
+
+func (s selector) Subscriptions() []domain.Subscription {
+	return []domain.Subscription{
+		domain.SubscribeGroup[domain.UpdateCompleted](quotes.Group),
+		domain.SubscribeGroup[domain.UpdateCompleted](dividends.Group),
+	}
+}
+
+func (s selector) Produces() []domain.ID {
+	return []domain.ID{domain.GroupID(Group)}
+}
+
+func (s selector) Select(_ context.Context, event domain.Event) (ids []domain.ID, err error) {
+	switch selected := event.(type) {
+	case domain.UpdateCompleted:
+		if selected.Group() == quotes.Group || selected.Group() == dividends.Group {
+			ids = append(ids, ID(string(selected.Name())))
+		}
+	}
+
+	return ids, err
+}
@@ -0,0 +1,21 @@
+package totalreturn
+
+import (
+	"fmt"
+	"github.com/WLM1ke/poptimizer/data/internal/domain"
+	"github.com/WLM1ke/poptimizer/data/internal/rules/template"
+)
+
+func validator(_ domain.Table[TotalReturn], rows []TotalReturn) error {
+	prev := rows[0].Date
+	for _, row := range rows[1:] {
+		if prev.Before(row.Date) {
+			prev = row.Date
+			continue
+		}
+
+		return fmt.Errorf("%w: not increasing dates %+v and %+v", template.ErrNewRowsValidation, prev, row.Date)
+	}
+
+	return nil
+}
@@ -22,7 +22,7 @@ func validator(table domain.Table[gomoex.Candle], rows []gomoex.Candle) error {
 		return nil
 	}
 
-	if table.LastRow() != rows[0] {
+	if !table.LastRow().Begin.Equal(rows[0].Begin) {
 		return fmt.Errorf(
 			"%w: old rows %+v not match new %+v",
 			template.ErrNewRowsValidation,
@@ -30,5 +30,13 @@ func validator(table domain.Table[gomoex.Candle], rows []gomoex.Candle) error {
 			rows[0])
 	}
 
+	if table.LastRow() != rows[0] {
+		return fmt.Errorf(
+			"%w: old rows %+v not match new %+v",
+			template.ErrHistoryRevision,
+			table.LastRow(),
+			rows[0])
+	}
+
 	return nil
 }
@@ -1,11 +1,88 @@
 package client
 
-import "net/http"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/time/rate"
+)
+
+const _rateLimitSeparator = ":"
+
+// ErrRateLimit - некорректное описание ограничения частоты запросов.
+var ErrRateLimit = errors.New("bad rate limit")
+
+// NewHTTPClient создает клиент с ограничением количества соединений и частоты запросов к отдельным хостам.
+//
+// Ограничения частоты задаются в формате host:rps:burst и реализуются в виде корзины маркеров - запросы сверх
+// ограничения ожидают свободного маркера или отмены контекста запроса.
+func NewHTTPClient(maxConnsPerHost int, rateLimits []string) (*http.Client, error) {
+	limiters, err := parseRateLimits(rateLimits)
+	if err != nil {
+		return nil, err
+	}
 
-func NewHTTPClient(maxConnsPerHost int) *http.Client {
 	return &http.Client{
-		Transport: &http.Transport{
-			MaxConnsPerHost: maxConnsPerHost,
+		Transport: rateLimitedTransport{
+			next: &http.Transport{
+				MaxConnsPerHost: maxConnsPerHost,
+			},
+			limiters: limiters,
 		},
+	}, nil
+}
+
+func parseRateLimits(rateLimits []string) (map[string]*rate.Limiter, error) {
+	limiters := make(map[string]*rate.Limiter, len(rateLimits))
+
+	for _, raw := range rateLimits {
+		if raw == "" {
+			continue
+		}
+
+		parts := strings.Split(raw, _rateLimitSeparator)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("%w: %s", ErrRateLimit, raw)
+		}
+
+		rps, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil || rps <= 0 {
+			return nil, fmt.Errorf("%w: %s", ErrRateLimit, raw)
+		}
+
+		burst, err := strconv.Atoi(parts[2])
+		if err != nil || burst < 1 {
+			return nil, fmt.Errorf("%w: %s", ErrRateLimit, raw)
+		}
+
+		limiters[parts[0]] = rate.NewLimiter(rate.Limit(rps), burst)
+	}
+
+	return limiters, nil
+}
+
+// rateLimitedTransport ограничивает частоту запросов к хостам, для которых заданы ограничения.
+type rateLimitedTransport struct {
+	next     http.RoundTripper
+	limiters map[string]*rate.Limiter
+}
+
+func (t rateLimitedTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if limiter, ok := t.limiters[request.URL.Hostname()]; ok {
+		if err := limiter.Wait(request.Context()); err != nil {
+			return nil, fmt.Errorf("rate limit for %s -> %w", request.URL.Hostname(), err)
+		}
+	}
+
+	return t.next.RoundTrip(request)
+}
+
+// CloseIdleConnections закрывает неиспользуемые соединения.
+func (t rateLimitedTransport) CloseIdleConnections() {
+	if closer, ok := t.next.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
 	}
 }
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRateLimits(t *testing.T) {
+	tbl := []struct {
+		limits []string
+		hosts  int
+		err    error
+	}{
+		{[]string{"iss.moex.com:10:10", "rosstat.gov.ru:0.5:1"}, 2, nil},
+		{[]string{""}, 0, nil},
+		{[]string{"iss.moex.com:10"}, 0, ErrRateLimit},
+		{[]string{"iss.moex.com:0:10"}, 0, ErrRateLimit},
+		{[]string{"iss.moex.com:10:0"}, 0, ErrRateLimit},
+	}
+
+	for _, testCase := range tbl {
+		limiters, err := parseRateLimits(testCase.limits)
+
+		assert.ErrorIs(t, err, testCase.err, "Некорректная ошибка разбора ограничений")
+		assert.Equal(t, testCase.hosts, len(limiters), "Некорректное количество ограничений")
+	}
+}
+
+func TestRateLimitedTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+	defer srv.Close()
+
+	client, err := NewHTTPClient(1, []string{"127.0.0.1:20:1"})
+	assert.Nil(t, err, "Ошибка создания клиента")
+
+	start := time.Now()
+
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(srv.URL)
+		assert.Nil(t, err, "Ошибка запроса")
+		resp.Body.Close()
+	}
+
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond, "Не соблюдено ограничение частоты запросов")
+}
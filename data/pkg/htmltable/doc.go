@@ -0,0 +1,2 @@
+// Package htmltable содержит функции извлечения данных из таблиц html-страниц.
+package htmltable
@@ -0,0 +1,121 @@
+package htmltable
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	// ErrTableNotFound - на странице отсутствует таблица с заданным номером.
+	ErrTableNotFound = errors.New("html table not found")
+	// ErrColumnNotFound - в заголовке таблицы отсутствует столбец с заданным названием.
+	ErrColumnNotFound = errors.New("html table column not found")
+)
+
+// Parse извлекает текстовое содержимое ячеек таблицы с заданным порядковым номером на странице.
+//
+// Каждая строка результата соответствует тегу tr, а ячейки - тегам th и td. Пробельные символы внутри ячеек
+// схлопываются. Вложенные таблицы учитываются при подсчете номера, но их строки не попадают в родительскую таблицу.
+func Parse(reader io.Reader, index int) ([][]string, error) {
+	root, err := html.Parse(reader)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse html -> %w", err)
+	}
+
+	count := 0
+
+	table := find(root, func(node *html.Node) bool {
+		if !isElement(node, "table") {
+			return false
+		}
+
+		count++
+
+		return count == index+1
+	})
+	if table == nil {
+		return nil, fmt.Errorf("%w: index %d", ErrTableNotFound, index)
+	}
+
+	var rows [][]string
+
+	walkRows(table, func(tr *html.Node) {
+		var row []string
+
+		for cell := tr.FirstChild; cell != nil; cell = cell.NextSibling {
+			if isElement(cell, "td") || isElement(cell, "th") {
+				row = append(row, text(cell))
+			}
+		}
+
+		rows = append(rows, row)
+	})
+
+	return rows, nil
+}
+
+func isElement(node *html.Node, tag string) bool {
+	return node.Type == html.ElementNode && node.Data == tag
+}
+
+func find(node *html.Node, match func(*html.Node) bool) *html.Node {
+	if match(node) {
+		return node
+	}
+
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if found := find(child, match); found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
+
+func walkRows(node *html.Node, fn func(*html.Node)) {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		switch {
+		case isElement(child, "table"):
+			continue
+		case isElement(child, "tr"):
+			fn(child)
+		default:
+			walkRows(child, fn)
+		}
+	}
+}
+
+func text(node *html.Node) string {
+	var builder strings.Builder
+
+	var collect func(*html.Node)
+	collect = func(node *html.Node) {
+		if node.Type == html.TextNode {
+			builder.WriteString(node.Data)
+			builder.WriteString(" ")
+		}
+
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			collect(child)
+		}
+	}
+
+	collect(node)
+
+	return strings.Join(strings.Fields(builder.String()), " ")
+}
+
+// Column находит номер первого столбца, название которого содержит заданную подстроку.
+func Column(header []string, name string) (int, error) {
+	for n, cell := range header {
+		if strings.Contains(cell, name) {
+			return n, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: %s in %q", ErrColumnNotFound, name, header)
+}
@@ -0,0 +1,45 @@
+package htmltable
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const _page = `<html><body>
+<table><tr><td>skip</td></tr></table>
+<table>
+	<thead><tr><th>Тикер</th><th> Дата
+		закрытия </th></tr></thead>
+	<tbody>
+		<tr><td><a href="#">AKRN</a></td><td>01.06.2022<table><tr><td>nested</td></tr></table></td></tr>
+	</tbody>
+</table>
+</body></html>`
+
+func TestParse(t *testing.T) {
+	rows, err := Parse(strings.NewReader(_page), 1)
+
+	assert.Nil(t, err, "Ошибка при разборе таблицы")
+	assert.Equal(
+		t,
+		[][]string{{"Тикер", "Дата закрытия"}, {"AKRN", "01.06.2022 nested"}},
+		rows,
+		"Некорректное содержимое таблицы",
+	)
+
+	_, err = Parse(strings.NewReader(_page), 3)
+	assert.ErrorIs(t, err, ErrTableNotFound, "Не обнаружено отсутствие таблицы")
+}
+
+func TestColumn(t *testing.T) {
+	header := []string{"Тикер", "Дата закрытия реестра"}
+
+	n, err := Column(header, "закрытия")
+	assert.Nil(t, err, "Ошибка при поиске столбца")
+	assert.Equal(t, 1, n, "Некорректный номер столбца")
+
+	_, err = Column(header, "Дивиденд")
+	assert.ErrorIs(t, err, ErrColumnNotFound, "Не обнаружено отсутствие столбца")
+}
@@ -2,13 +2,34 @@ package server
 
 import (
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/WLM1ke/poptimizer/data/pkg/lgr"
+	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
-// Middleware реализует логирование запроса.
+var (
+	requestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Number of HTTP requests by method, route and status code.",
+		},
+		[]string{"method", "route", "code"},
+	)
+	requestDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "http_request_duration_seconds",
+			Help: "Duration of HTTP requests by method and route.",
+		},
+		[]string{"method", "route"},
+	)
+)
+
+// Middleware реализует логирование запроса и учет статистики запросов.
 func Middleware(logger *lgr.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		handlerFunc := func(writer http.ResponseWriter, request *http.Request) {
@@ -16,6 +37,16 @@ func Middleware(logger *lgr.Logger) func(http.Handler) http.Handler {
 			start := time.Now()
 
 			defer func() {
+				// Если обработчик ничего не записал, сервер отвечает со статусом 200
+				status := writerWithStats.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+
+				route := routePattern(request)
+				requestsTotal.WithLabelValues(request.Method, route, strconv.Itoa(status)).Inc()
+				requestDuration.WithLabelValues(request.Method, route).Observe(time.Since(start).Seconds())
+
 				logger.Infof(
 					"Request: %s %s %d %db %s",
 					request.Method,
@@ -32,3 +63,14 @@ func Middleware(logger *lgr.Logger) func(http.Handler) http.Handler {
 		return http.HandlerFunc(handlerFunc)
 	}
 }
+
+// routePattern - шаблон маршрута запроса, чтобы количество временных рядов метрик не зависело от параметров запросов.
+func routePattern(request *http.Request) string {
+	if rctx := chi.RouteContext(request.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			return pattern
+		}
+	}
+
+	return "unknown"
+}